@@ -0,0 +1,52 @@
+package set
+
+// Builder accumulates items for a set which is built once, e.g. from a huge
+// stream of mostly unique items. Adding to a Builder only appends to a slice;
+// the items are deduplicated by Build, into a map sized up front, which saves
+// the repeated growth of the map when adding to a set item by item. The zero
+// value builds a thread-safe set.
+type Builder[T comparable] struct {
+	setType SetType
+	items   []T
+}
+
+// NewBuilder creates a Builder for a set of the given type, either ThreadSafe
+// or NonThreadSafe.
+func NewBuilder[T comparable](setType SetType) *Builder[T] {
+	return &Builder[T]{setType: setType}
+}
+
+// Add appends items to the builder. Duplicates are kept until Build.
+func (b *Builder[T]) Add(items ...T) {
+	b.items = append(b.items, items...)
+}
+
+// Len returns the number of items added since the last Build, including
+// duplicates.
+func (b *Builder[T]) Len() int {
+	return len(b.items)
+}
+
+// Build returns a set of the builder's type with the items added so far, and
+// resets the builder.
+func (b *Builder[T]) Build() Set[T] {
+	var s *set[T]
+	var result Set[T]
+	if b.setType == NonThreadSafe {
+		u := newNonTS[T]()
+		s, result = &u.set, u
+	} else {
+		u := newTS[T]()
+		s, result = &u.set, u
+	}
+
+	s.m = make(map[T]T, len(b.items))
+	for _, item := range b.items {
+		s.m[item] = item
+	}
+	s.hint = len(b.items)
+	s.changed()
+
+	b.items = nil
+	return result
+}
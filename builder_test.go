@@ -0,0 +1,55 @@
+package set
+
+import "testing"
+
+func TestBuilder(t *testing.T) {
+	b := NewBuilder[int](NonThreadSafe)
+	b.Add(1, 2, 3)
+	b.Add(3, 4)
+	if b.Len() != 5 {
+		t.Errorf("Builder: Len should count duplicates, want 5, got %d", b.Len())
+	}
+
+	s := b.Build()
+	if _, ok := s.(*SetNonTS[int]); !ok {
+		t.Errorf("Builder: Build should return a non-thread-safe set, got %T", s)
+	}
+	want := newNonTS[int]()
+	want.Add(1, 2, 3, 4)
+	if !s.IsEqual(want) {
+		t.Error("Builder: Build should return the deduplicated items, got", s)
+	}
+
+	if b.Len() != 0 || !b.Build().IsEmpty() {
+		t.Error("Builder: Build should reset the builder")
+	}
+
+	var zero Builder[int]
+	zero.Add(1)
+	if s := zero.Build(); !s.Has(1) {
+		t.Error("Builder: the zero value should build a set with the item")
+	} else if _, ok := s.(*SetTS[int]); !ok {
+		t.Errorf("Builder: the zero value should build a thread-safe set, got %T", s)
+	}
+}
+
+const benchmarkStreamSize = 10_000_000
+
+func BenchmarkStreamAdd(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		s := newNonTS[int]()
+		for j := 0; j < benchmarkStreamSize; j++ {
+			s.Add(j)
+		}
+	}
+}
+
+func BenchmarkStreamBuilder(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		bld := NewBuilder[int](NonThreadSafe)
+		for j := 0; j < benchmarkStreamSize; j++ {
+			bld.Add(j)
+		}
+		bld.Build()
+	}
+}
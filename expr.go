@@ -0,0 +1,97 @@
+package set
+
+// Expr is an expression of set algebra, built fluently and evaluated at once
+// by Build:
+//
+//	s := set.Of(a).Minus(b).Plus(c).Build()
+//
+// Unlike nesting calls to Difference and Union, no intermediate set is built:
+// Build traverses each set which may contribute items once, and checks each
+// item against the whole chain of operations. Operations apply left to right,
+// so the expression above is (a - b) + c.
+type Expr[T comparable] struct {
+	base Set[T]
+	ops  []exprOp[T]
+}
+
+// exprOp is an operation recorded by Expr: the union with s, or the
+// difference with s if minus is true.
+type exprOp[T comparable] struct {
+	s     Set[T]
+	minus bool
+}
+
+// Of starts an expression of set algebra with the items of s. A nil s is
+// treated as an empty set.
+func Of[T comparable](s Set[T]) *Expr[T] {
+	return &Expr[T]{base: s}
+}
+
+// Plus adds the items of s to the result of the expression so far. It
+// modifies and returns e.
+func (e *Expr[T]) Plus(s Set[T]) *Expr[T] {
+	e.ops = append(e.ops, exprOp[T]{s: s})
+	return e
+}
+
+// Minus removes the items of s from the result of the expression so far. It
+// modifies and returns e.
+func (e *Expr[T]) Minus(s Set[T]) *Expr[T] {
+	e.ops = append(e.ops, exprOp[T]{s: s, minus: true})
+	return e
+}
+
+// Build evaluates the expression into a new set of the type of the set passed
+// to Of. All sets of the expression are read-locked, in the order described at
+// lockTwo, while it's evaluated.
+func (e *Expr[T]) Build() Set[T] {
+	locks := make([]RWLockable, 0, len(e.ops)+1)
+	l, base := lockerOf(e.base)
+	locks = append(locks, l)
+	views := make([]Set[T], len(e.ops))
+	for i, op := range e.ops {
+		l, views[i] = lockerOf(op.s)
+		locks = append(locks, l)
+	}
+	unlock := rLockAll(locks...)
+	defer unlock()
+
+	// probe is passed to Has for every item, as passing the item itself would
+	// allocate a variadic slice each time.
+	probe := make([]T, 1)
+	var items []T
+
+	// Only the items of the base and of the sets added by Plus may be in the
+	// result. Each such item is in the result right after the operation which
+	// contributed it, so only the operations following it need checking.
+	evaluate := func(from int) func(T) bool {
+		return func(item T) bool {
+			in := true
+			probe[0] = item
+			for i := from; i < len(e.ops); i++ {
+				if e.ops[i].minus {
+					in = in && !views[i].Has(probe...)
+				} else {
+					in = in || views[i].Has(probe...)
+				}
+			}
+			if in {
+				items = append(items, item)
+			}
+			return true
+		}
+	}
+
+	base.Each(evaluate(0))
+	for i, op := range e.ops {
+		if !op.minus {
+			views[i].Each(evaluate(i + 1))
+		}
+	}
+
+	// Adding the items at once saves the allocation of Add's variadic slice
+	// for each of them.
+	result := newLike(e.base)
+	result.AddAll(items...)
+	return result
+}
@@ -0,0 +1,66 @@
+package set
+
+import "testing"
+
+func TestExpr(t *testing.T) {
+	a := newTS[int]()
+	a.Add(1, 2, 3, 4)
+	b := newNonTS[int]()
+	b.Add(2, 5)
+	c := newNonTS[int]()
+	c.Add(2, 6, 7)
+	d := newTS[int]()
+	d.Add(3, 7)
+
+	r := Of[int](a).Minus(b).Plus(c).Minus(d).Build()
+	want := Difference[int](Union[int](Difference[int](a, b), c), d)
+	if !r.IsEqual(want) || r.Size() != 4 || !r.Has(1, 2, 4, 6) {
+		t.Error("Expr: should be ((a - b) + c) - d, got", r)
+	}
+	if _, ok := r.(*SetTS[int]); !ok {
+		t.Errorf("Expr: the result should be of the type of a, got %T", r)
+	}
+
+	if r := Of[int](nil).Plus(b).Minus(nil).Build(); !r.IsEqual(b) {
+		t.Error("Expr: nil sets should be treated as empty, got", r)
+	}
+
+	if r := Of[int](a).Minus(a).Plus(a).Build(); !r.IsEqual(a) {
+		t.Error("Expr: a - a + a should be a, got", r)
+	}
+
+	if r := Of[int](a).Build(); !r.IsEqual(a) || r == Set[int](a) {
+		t.Error("Expr: building a bare set should copy it, got", r)
+	}
+}
+
+func benchmarkExprSets() []Set[int] {
+	sets := make([]Set[int], 6)
+	for i := range sets {
+		sets[i] = newNonTS[int]()
+		for j := 0; j < 10000; j++ {
+			sets[i].Add(i*2000 + j)
+		}
+	}
+	return sets
+}
+
+func BenchmarkExprChain(b *testing.B) {
+	s := benchmarkExprSets()
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		Of(s[0]).Minus(s[1]).Plus(s[2]).Minus(s[3]).Plus(s[4]).Minus(s[5]).Build()
+	}
+}
+
+func BenchmarkExprNested(b *testing.B) {
+	s := benchmarkExprSets()
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		Difference(Union(Difference(Union(Difference(s[0], s[1]), s[2]), s[3]), s[4]), s[5])
+	}
+}
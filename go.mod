@@ -1,3 +1,3 @@
 module github.com/latavin243/set
 
-go 1.19
+go 1.23
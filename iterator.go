@@ -0,0 +1,37 @@
+package set
+
+// Iterator is a stateful iterator over a snapshot of the items of a set. It
+// allows iterating with manual control, e.g. stopping and resuming later:
+//
+//	it := s.Iterator()
+//	for it.Next() {
+//		item := it.Value()
+//		...
+//	}
+type Iterator[T comparable] struct {
+	items []T
+	pos   int
+}
+
+func newIterator[T comparable](items []T) *Iterator[T] {
+	return &Iterator[T]{items: items, pos: -1}
+}
+
+// Next advances the iterator to the next item. It returns false once all items
+// have been visited.
+func (it *Iterator[T]) Next() bool {
+	if it.pos < len(it.items) {
+		it.pos++
+	}
+	return it.pos < len(it.items)
+}
+
+// Value returns the current item. It returns the zero value of T if Next
+// hasn't been called yet or has returned false.
+func (it *Iterator[T]) Value() T {
+	if it.pos < 0 || it.pos >= len(it.items) {
+		var zeroVal T
+		return zeroVal
+	}
+	return it.items[it.pos]
+}
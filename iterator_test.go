@@ -0,0 +1,29 @@
+package set
+
+import "testing"
+
+func TestIterator(t *testing.T) {
+	for _, s := range []Set[string]{newTS[string](), newNonTS[string]()} {
+		s.Add("1", "2", "3")
+
+		it := s.Iterator()
+		if it.Value() != "" {
+			t.Error("Iterator: Value should be the zero value before calling Next")
+		}
+
+		s.Add("4") // not in the snapshot
+
+		r := newNonTS[string]()
+		for it.Next() {
+			r.Add(it.Value())
+		}
+
+		if r.Size() != 3 || !r.Has("1", "2", "3") {
+			t.Error("Iterator: should visit the three items of the snapshot, got", r)
+		}
+
+		if it.Next() {
+			t.Error("Iterator: Next should keep returning false once exhausted")
+		}
+	}
+}
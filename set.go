@@ -3,8 +3,35 @@
 // operations on one set. Operations on multiple sets are consistent in that
 // the elements of each set used was valid at exactly one point in time
 // between the start and the end of the operation.
+//
+// The thread-safe set holds its read lock while calling the closure passed to
+// Each and similar methods. The closure must therefore not modify the set it's
+// traversing: its write lock can't be acquired until the traversal ends,
+// which deadlocks. Use EachSnapshot instead, which calls the closure without
+// holding the lock.
+//
+// A nil Set passed to any function or method is treated as an empty set. The
+// only exception is a nil set which would be modified, like the dst of
+// UnionInto, which panics with a message naming the parameter.
 package set
 
+import (
+	"bufio"
+	"cmp"
+	"errors"
+	"fmt"
+	"io"
+	"iter"
+	"math"
+	"reflect"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
 // SetType denotes which type of set is created. ThreadSafe or NonThreadSafe
 type SetType int
 
@@ -26,21 +53,65 @@ func (s SetType) String() string {
 // Set is an unordered, unique list of values.
 type Set[T comparable] interface {
 	Add(items ...T)
+	AddNew(items ...T) Set[T]
+	Intern(item T) T
+	AddAll(items ...T)
+	Toggle(items ...T)
+	ToggleReport(items ...T) []bool
 	Remove(items ...T)
+	RemoveReturning(items ...T) Set[T]
+	RemoveReport(items ...T) (removed, notFound []T)
 	Pop() (T, bool)
+	PopHint(hint T) (T, bool)
+	PopWhere(pred func(T) bool) Set[T]
 	Has(items ...T) bool
+	HasAll(items ...T) bool
+	HasAny(items ...T) bool
+	HasEach(items ...T) []bool
+	Get(item T) (T, bool)
 	Size() int
+	OnSizeThreshold(n int, f func(size int))
+	Cap() int
+	Grow(n int)
+	EstimatedBytes() int
 	Clear()
+	Drain() []T
+	Compact()
+	NormalizeInPlace(f func(item T) T)
 	IsEmpty() bool
 	IsEqual(s Set[T]) bool
 	IsSubset(s Set[T]) bool
 	IsSuperset(s Set[T]) bool
+	ContainsSet(s Set[T]) bool
+	ContainsAnySet(s Set[T]) bool
 	Each(func(T) bool)
+	EachSnapshot(func(T) bool)
+	EachErr(func(T) error) error
+	EachIndexed(func(int, T) bool)
+	EachBatch(batchSize int, f func([]T) bool)
+	EachParallel(workers int, f func(T))
+	Iterator() *Iterator[T]
+	Keys() iter.Seq[T]
 	String() string
+	StringFunc(sep string, format func(T) string) string
+	WriteTo(w io.Writer) (int64, error)
+	Hash() uint64
 	List() []T
+	Stream() <-chan T
+	Tee(n int) []<-chan T
+	NewEmpty() Set[T]
 	Copy() Set[T]
+	CopyWithCap(extra int) Set[T]
+	Snapshot() Set[T]
+	SplitN(n int) []Set[T]
+	AsThreadSafe() Set[T]
+	AsNonThreadSafe() Set[T]
 	Merge(s Set[T])
 	Separate(s Set[T])
+	RetainSlice(items []T)
+	DifferenceSlice(items []T) Set[T]
+	IsSubsetSlice(items []T) bool
+	IsSupersetSlice(items []T) bool
 }
 
 // RWLockable is an interface that provides read/write locking capabilities to a set.
@@ -51,6 +122,194 @@ type RWLockable interface {
 	RUnlock()
 }
 
+// lockedViewer is implemented by sets other than SetTS whose methods lock
+// themselves, like SetAdaptive.
+type lockedViewer[T comparable] interface {
+	// lockedView returns the lock of the set, or nil if it isn't
+	// thread-safe, and a view of it whose methods don't lock it.
+	lockedView() (RWLockable, Set[T])
+	threadSafe() bool
+}
+
+// comparer is implemented by sets whose comparisons have a variant which
+// doesn't lock the argument, for callers which have locked it already.
+type comparer[T comparable] interface {
+	isEqual(t Set[T]) bool
+	isSubset(t Set[T]) bool
+	containsAnySet(t Set[T]) bool
+}
+
+// lockerOf returns the lock of t, or nil if it isn't lockable, along with a
+// view of t whose methods can be called without locking it again once the
+// lock is held. A nil t is viewed as an empty set.
+func lockerOf[T comparable](t Set[T]) (RWLockable, Set[T]) {
+	switch conv := t.(type) {
+	case nil:
+		return nil, orEmpty(t)
+	case *SetTS[T]:
+		return &conv.l, &conv.set
+	case lockedViewer[T]:
+		return conv.lockedView()
+	case RWLockable:
+		return conv, t
+	}
+	return nil, t
+}
+
+// readLocked read-locks t if it's lockable, and returns a view of it whose
+// methods can be called without locking it again along with the function
+// releasing the lock.
+func readLocked[T comparable](t Set[T]) (Set[T], func()) {
+	l, view := lockerOf(t)
+	return view, rLockOne(l)
+}
+
+// writeLocked is like readLocked, but write-locks t.
+func writeLocked[T comparable](t Set[T]) (Set[T], func()) {
+	l, view := lockerOf(t)
+	return view, lockTwo(l, nil)
+}
+
+// addingTo calls f with a view of s, which adds items to it, under the write
+// lock of s if it's lockable. Unlike with writeLocked, the callbacks registered
+// by OnSizeThreshold are checked, and called once the lock is released, as
+// the unlocked views of SetTS and SetAdaptive don't.
+func addingTo[T comparable](s Set[T], f func(Set[T])) {
+	if a, ok := s.(interface{ addingView(func(Set[T])) }); ok {
+		a.addingView(f)
+		return
+	}
+
+	s, unlock := writeLocked(s)
+	defer unlock()
+	f(s)
+}
+
+// readLockedTwo is like readLocked, but read-locks both a and b, in the order
+// described at lockTwo.
+func readLockedTwo[T comparable](a, b Set[T]) (Set[T], Set[T], func()) {
+	la, viewA := lockerOf(a)
+	lb, viewB := lockerOf(b)
+	return viewA, viewB, rLockTwo(la, lb)
+}
+
+// Whenever several sets are locked at once, their locks are taken in the
+// order of their addresses, lowest first, regardless of which are read or
+// written. So goroutines locking the same sets in different roles, like
+// a.Merge(b) and b.Merge(a), can't deadlock waiting for each other. Read
+// locks need ordering too, as a pending writer blocks new readers.
+
+// lockAddr returns the address lockTwo and rLockTwo order l by. Locks which
+// aren't pointers all share address zero, and are never considered the same.
+func lockAddr(l RWLockable) uintptr {
+	if v := reflect.ValueOf(l); v.Kind() == reflect.Pointer {
+		return v.Pointer()
+	}
+	return 0
+}
+
+// sameLock reports whether a and b are the same lock.
+func sameLock(a, b RWLockable) bool {
+	addr := lockAddr(a)
+	return addr != 0 && addr == lockAddr(b)
+}
+
+// rLockOne read-locks l, unless it's nil, and returns the function releasing
+// it.
+func rLockOne(l RWLockable) func() {
+	if l == nil {
+		return func() {}
+	}
+	l.RLock()
+	return l.RUnlock
+}
+
+// lockTwo write-locks w and read-locks r in address order, and returns the
+// function releasing both. Either may be nil, for a set which isn't lockable,
+// and if both are the same lock it's only write-locked.
+func lockTwo(w, r RWLockable) func() {
+	switch {
+	case w == nil:
+		return rLockOne(r)
+	case r == nil || sameLock(w, r):
+		w.Lock()
+		return w.Unlock
+	case lockAddr(w) < lockAddr(r):
+		w.Lock()
+		r.RLock()
+	default:
+		r.RLock()
+		w.Lock()
+	}
+	return func() {
+		r.RUnlock()
+		w.Unlock()
+	}
+}
+
+// rLockTwo read-locks a and b in address order, and returns the function
+// releasing both. Either may be nil, for a set which isn't lockable, and if
+// both are the same lock it's only locked once.
+func rLockTwo(a, b RWLockable) func() {
+	switch {
+	case a == nil:
+		return rLockOne(b)
+	case b == nil || sameLock(a, b):
+		return rLockOne(a)
+	}
+
+	if lockAddr(b) < lockAddr(a) {
+		a, b = b, a
+	}
+	a.RLock()
+	b.RLock()
+	return func() {
+		b.RUnlock()
+		a.RUnlock()
+	}
+}
+
+// rLockAll is like rLockTwo, but for any number of locks.
+func rLockAll(ls ...RWLockable) func() {
+	ls = slices.DeleteFunc(slices.Clone(ls), func(l RWLockable) bool { return l == nil })
+	slices.SortStableFunc(ls, func(a, b RWLockable) int { return cmp.Compare(lockAddr(a), lockAddr(b)) })
+	ls = slices.CompactFunc(ls, sameLock)
+
+	for _, l := range ls {
+		l.RLock()
+	}
+	return func() {
+		for i := len(ls) - 1; i >= 0; i-- {
+			ls[i].RUnlock()
+		}
+	}
+}
+
+// orEmpty returns s, or an empty set if s is nil.
+func orEmpty[T comparable](s Set[T]) Set[T] {
+	if s == nil {
+		return newFrozen(map[T]T{})
+	}
+	return s
+}
+
+// orEmptyAll is like orEmpty for each of sets, returning a new slice.
+func orEmptyAll[T comparable](sets ...Set[T]) []Set[T] {
+	all := make([]Set[T], len(sets))
+	for i, set := range sets {
+		all[i] = orEmpty(set)
+	}
+	return all
+}
+
+// mustNotBeNil panics if s, the parameter named param of the function named
+// fn, is nil.
+func mustNotBeNil[T comparable](fn, param string, s Set[T]) {
+	if s == nil {
+		panic("set: " + fn + " called with a nil " + param)
+	}
+}
+
 // helpful to not write everywhere struct{}{}
 var keyExists = struct{}{}
 
@@ -64,32 +323,183 @@ func New[T comparable](setType SetType) Set[T] {
 	return newTS[T]()
 }
 
+// NewCap creates and initializes a new Set like New, with its backing map
+// preallocated to hold capacity items. This saves growing the map item by
+// item when the eventual size is roughly known. A capacity of zero or less
+// behaves like New.
+func NewCap[T comparable](setType SetType, capacity int) Set[T] {
+	s := New[T](setType)
+	s.Grow(capacity)
+	return s
+}
+
+// iterationSeed is the seed set by SetIterationSeed, or nil if it's disabled.
+var iterationSeed atomic.Pointer[int64]
+
+// SetIterationSeed makes the traversal order of all sets deterministic, given
+// the seed and the items of a set. This is meant for tests relying on the
+// order of List, Each and the methods built on them, like golden tests, as
+// Go randomizes the order of maps. It's disabled by default, and costs a sort
+// of the items on every traversal while enabled.
+func SetIterationSeed(seed int64) {
+	iterationSeed.Store(&seed)
+}
+
+// ClearIterationSeed disables the deterministic traversal order enabled by
+// SetIterationSeed.
+func ClearIterationSeed() {
+	iterationSeed.Store(nil)
+}
+
+// setTypeOf returns the SetType of the implementation of s.
+func setTypeOf[T comparable](s Set[T]) SetType {
+	switch conv := s.(type) {
+	case *SetTS[T]:
+		return ThreadSafe
+	case lockedViewer[T]:
+		if conv.threadSafe() {
+			return ThreadSafe
+		}
+	}
+	return NonThreadSafe
+}
+
+// newLike returns a new empty set of the same implementation as s, as
+// returned by its NewEmpty method, for functions returning a set of the type
+// of their input. A nil s gets a non-thread-safe set.
+func newLike[T comparable](s Set[T]) Set[T] {
+	if s == nil {
+		return newNonTS[T]()
+	}
+	return s.NewEmpty()
+}
+
+// NewFromChannel creates a new Set of the given type and adds every value
+// received from ch to it. It blocks until ch is closed.
+func NewFromChannel[T comparable](setType SetType, ch <-chan T) Set[T] {
+	s := New[T](setType)
+	for item := range ch {
+		s.Add(item)
+	}
+	return s
+}
+
+// NewFromSlices creates a new Set of the given type holding the items of all
+// of the given slices. The set is preallocated to the sum of their lengths.
+func NewFromSlices[T comparable](setType SetType, items ...[]T) Set[T] {
+	total := 0
+	for _, slice := range items {
+		total += len(slice)
+	}
+
+	s := New[T](setType)
+	s.Grow(total)
+	for _, slice := range items {
+		s.Add(slice...)
+	}
+	return s
+}
+
+// NewFromSyncMap creates a new Set of the given type holding all keys of m.
+// The values of m are ignored, as are keys which aren't of type K. The set
+// reflects m as it's seen while ranging over it, with the same consistency
+// guarantees as sync.Map.Range.
+func NewFromSyncMap[K comparable](setType SetType, m *sync.Map) Set[K] {
+	s := New[K](setType)
+	m.Range(func(key, _ any) bool {
+		if k, ok := key.(K); ok {
+			s.Add(k)
+		}
+		return true
+	})
+	return s
+}
+
 // Union is the merger of multiple sets. It returns a new set with all the
 // elements present in all the sets that are passed.
 //
+// The result is preallocated to the sum of the sizes of the sets, but at most
+// twice the size of the largest one, so that heavily overlapping sets don't
+// over-reserve.
+//
 // The dynamic type of the returned set is determined by the first passed set's
-// implementation of the New() method.
+// implementation of the NewEmpty() method.
 func Union[T comparable](set1, set2 Set[T], sets ...Set[T]) Set[T] {
-	u := set1.Copy()
-	set2.Each(func(item T) bool {
-		u.Add(item)
-		return true
-	})
+	all := orEmptyAll(append([]Set[T]{set1, set2}, sets...)...)
+
+	total, largest := 0, 0
+	for _, set := range all {
+		size := set.Size()
+		total += size
+		largest = max(largest, size)
+	}
+
+	u := newLike(set1)
+	u.Grow(min(total, 2*largest))
+	for _, set := range all {
+		u.Merge(set)
+	}
+	return u
+}
+
+// UnionAs is like Union, but the returned set is of the given type instead of
+// that of the first passed set. It's preallocated to the sum of the sizes of
+// the sets.
+func UnionAs[T comparable](setType SetType, sets ...Set[T]) Set[T] {
+	sets = orEmptyAll(sets...)
+
+	total := 0
 	for _, set := range sets {
-		set.Each(func(item T) bool {
-			u.Add(item)
-			return true
-		})
+		total += set.Size()
 	}
 
+	u := New[T](setType)
+	u.Grow(total)
+	for _, set := range sets {
+		u.Merge(set)
+	}
 	return u
 }
 
+// UnionInto merges all of the others sets into dst, modifying it in place
+// instead of allocating a new set like Union does. A thread-safe dst is locked
+// only once for all of the others.
+func UnionInto[T comparable](dst Set[T], others ...Set[T]) {
+	mustNotBeNil("UnionInto", "dst", dst)
+	others = orEmptyAll(others...)
+
+	ts, ok := dst.(*SetTS[T])
+	if !ok {
+		for _, set := range others {
+			dst.Merge(set)
+		}
+		return
+	}
+
+	// collect the items first so that no other set is locked while dst is
+	lists := make([][]T, 0, len(others))
+	for _, set := range others {
+		if set == dst {
+			continue
+		}
+		lists = append(lists, set.List())
+	}
+
+	ts.adding(func() {
+		for _, list := range lists {
+			for _, item := range list {
+				ts.m[item] = item
+			}
+		}
+		ts.changed()
+	})
+}
+
 // Difference returns a new set which contains items which are in the first
 // set but not in the others. Unlike the Difference() method you can use this
 // function separately with multiple sets.
 func Difference[T comparable](set1, set2 Set[T], sets ...Set[T]) Set[T] {
-	s := set1.Copy()
+	s := orEmpty(set1).Copy()
 	s.Separate(set2)
 	for _, set := range sets {
 		s.Separate(set) // separate is thread safe
@@ -97,23 +507,107 @@ func Difference[T comparable](set1, set2 Set[T], sets ...Set[T]) Set[T] {
 	return s
 }
 
-// Intersection returns a new set which contains items that only exist in all given sets.
+// Intersection returns a new set which contains items that only exist in all
+// given sets. The smallest of the sets is used as the seed, and each of its
+// items is probed in the other sets until one of them lacks it.
+//
+// The dynamic type of the returned set is that of the first passed set, like
+// with Union.
 func Intersection[T comparable](set1, set2 Set[T], sets ...Set[T]) Set[T] {
-	all := Union(set1, set2, sets...)
-	result := Union(set1, set2, sets...)
+	all := orEmptyAll(append([]Set[T]{set1, set2}, sets...)...)
+	seed := smallest(all)
 
-	all.Each(func(item T) bool {
-		if !set1.Has(item) || !set2.Has(item) {
-			result.Remove(item)
+	items := slices.DeleteFunc(all[seed].List(), func(item T) bool {
+		for i, set := range all {
+			if i != seed && !set.Has(item) {
+				return true
+			}
 		}
+		return false
+	})
 
-		for _, set := range sets {
-			if !set.Has(item) {
-				result.Remove(item)
+	result := newLike(set1)
+	result.Add(items...)
+	return result
+}
+
+// IntersectAll is like Intersection, but takes any number of sets. Starting
+// from the items of the smallest set, it drops those missing from each of the
+// others in turn, so later passes only scan what's left, and stops early once
+// nothing is left. With a single set it returns a copy of it, and with none an
+// empty non-thread-safe set.
+//
+// The dynamic type of the returned set is that of the first passed set, like
+// with Intersection.
+func IntersectAll[T comparable](sets ...Set[T]) Set[T] {
+	if len(sets) == 0 {
+		return newNonTS[T]()
+	}
+
+	all := orEmptyAll(sets...)
+	seed := smallest(all)
+
+	items := all[seed].List()
+	for i, set := range all {
+		if len(items) == 0 {
+			break
+		}
+		if i == seed || set == all[seed] {
+			continue
+		}
+
+		set, unlock := readLocked(set)
+		items = slices.DeleteFunc(items, func(item T) bool { return !set.Has(item) })
+		unlock()
+	}
+
+	result := newLike(sets[0])
+	result.Add(items...)
+	return result
+}
+
+// smallest returns the index of the smallest of sets, which must not be empty.
+func smallest[T comparable](sets []Set[T]) int {
+	index, size := 0, sets[0].Size()
+	for i, set := range sets[1:] {
+		if n := set.Size(); n < size {
+			index, size = i+1, n
+		}
+	}
+	return index
+}
+
+// DifferenceAs is like Difference, but the returned set is of the given type
+// instead of that of the first passed set.
+func DifferenceAs[T comparable](setType SetType, set1, set2 Set[T], sets ...Set[T]) Set[T] {
+	s := New[T](setType)
+	s.Merge(set1)
+	s.Separate(set2)
+	for _, set := range sets {
+		s.Separate(set)
+	}
+	return s
+}
+
+// IntersectionAs is like Intersection, but the returned set is of the given
+// type instead of that of the smallest passed set.
+func IntersectionAs[T comparable](setType SetType, set1, set2 Set[T], sets ...Set[T]) Set[T] {
+	all := orEmptyAll(append([]Set[T]{set1, set2}, sets...)...)
+	seed := smallest(all)
+
+	result := New[T](setType)
+	for _, item := range all[seed].List() {
+		inAll := true
+		for i, set := range all {
+			if i != seed && !set.Has(item) {
+				inAll = false
+				break
 			}
 		}
-		return true
-	})
+		if inAll {
+			result.Add(item)
+		}
+	}
 	return result
 }
 
@@ -124,3 +618,804 @@ func SymmetricDifference[T comparable](s, t Set[T]) Set[T] {
 	v := Difference(t, s)
 	return Union(u, v)
 }
+
+// EqualSlice reports whether s contains exactly the distinct elements of
+// items. The order of items and any duplicates in it are ignored.
+func EqualSlice[T comparable](s Set[T], items []T) bool {
+	missing, extra := DiffSlice(s, items)
+	return len(missing) == 0 && len(extra) == 0
+}
+
+// DiffSlice compares s against the distinct elements of items. It returns the
+// elements of items which are missing from s, and the extra elements of s
+// which are not in items. Both are empty if EqualSlice would return true.
+func DiffSlice[T comparable](s Set[T], items []T) (missing, extra []T) {
+	lookup := make(map[T]struct{}, len(items))
+	for _, item := range items {
+		lookup[item] = keyExists
+	}
+
+	seen := make(map[T]struct{}, len(lookup))
+	orEmpty(s).Each(func(item T) bool {
+		if _, ok := lookup[item]; ok {
+			seen[item] = keyExists
+		} else {
+			extra = append(extra, item)
+		}
+		return true
+	})
+
+	for _, item := range items {
+		if _, ok := seen[item]; ok {
+			continue
+		}
+		missing = append(missing, item)
+		seen[item] = keyExists // report duplicates only once
+	}
+	return missing, extra
+}
+
+// SymmetricDifferenceInto modifies dst in place to become the symmetric
+// difference of dst and t: items in both are removed from dst, and items only
+// in t are added to it. Unlike SymmetricDifference it works in a single pass
+// without allocating intermediate sets. A thread-safe dst is locked once.
+func SymmetricDifferenceInto[T comparable](dst, t Set[T]) {
+	mustNotBeNil("SymmetricDifferenceInto", "dst", dst)
+	t = orEmpty(t)
+
+	if dst == t {
+		dst.Clear()
+		return
+	}
+
+	items := t.List()
+
+	ts, ok := dst.(*SetTS[T])
+	if !ok {
+		for _, item := range items {
+			if dst.Has(item) {
+				dst.Remove(item)
+			} else {
+				dst.Add(item)
+			}
+		}
+		return
+	}
+
+	ts.adding(func() {
+		for _, item := range items {
+			if _, has := ts.m[item]; has {
+				delete(ts.m, item)
+			} else {
+				ts.m[item] = item
+			}
+		}
+		ts.changed()
+	})
+}
+
+// DeepCopy returns a new set of the same type as s, holding the result of
+// calling clone on each of its items. This is useful when T is a pointer type
+// and the pointed-to values shouldn't be shared like they are with Copy.
+//
+// clone must preserve the equality of items: if it maps distinct items to
+// equal ones, or equal items to distinct ones, the size of the returned set
+// differs from s.
+func DeepCopy[T comparable](s Set[T], clone func(T) T) Set[T] {
+	u := newLike(s)
+	orEmpty(s).Each(func(item T) bool {
+		u.Add(clone(item))
+		return true
+	})
+	return u
+}
+
+// Pair holds two related items, possibly of different types.
+type Pair[T, U comparable] struct {
+	First  T
+	Second U
+}
+
+// Join returns the inner join of a and b: a new set holding a Pair for every
+// item of a and item of b whose keys, as computed by keyA and keyB, are equal.
+// b is indexed by key first, so this runs in O(|a|+|b|) plus the size of the
+// result.
+//
+// The dynamic type of the returned set is determined by a.
+func Join[T, U, K comparable](a Set[T], b Set[U], keyA func(T) K, keyB func(U) K) Set[Pair[T, U]] {
+	index := make(map[K][]U)
+	orEmpty(b).Each(func(item U) bool {
+		k := keyB(item)
+		index[k] = append(index[k], item)
+		return true
+	})
+
+	result := New[Pair[T, U]](setTypeOf(a))
+	orEmpty(a).Each(func(item T) bool {
+		for _, match := range index[keyA(item)] {
+			result.Add(Pair[T, U]{item, match})
+		}
+		return true
+	})
+	return result
+}
+
+// FlatMap returns a new set holding all items of the slices returned by
+// calling f on each item of s. Duplicates are removed as usual, and empty
+// slices contribute nothing.
+//
+// The dynamic type of the returned set is determined by s.
+func FlatMap[T, U comparable](s Set[T], f func(T) []U) Set[U] {
+	result := New[U](setTypeOf(s))
+	orEmpty(s).Each(func(item T) bool {
+		result.Add(f(item)...)
+		return true
+	})
+	return result
+}
+
+// MapParallel returns a new set holding the results of calling f on each item
+// of s, from workers goroutines at once, like EachParallel does. This pays off
+// when f is expensive, e.g. it hashes or does I/O. f must be safe for
+// concurrent use. The results are collected into a thread-safe set, which is
+// handed over without copying if a non-thread-safe one is returned.
+//
+// The dynamic type of the returned set is determined by s.
+func MapParallel[T, U comparable](s Set[T], workers int, f func(T) U) Set[U] {
+	result := newTS[U]()
+	orEmpty(s).EachParallel(workers, func(item T) {
+		result.Add(f(item))
+	})
+
+	if setTypeOf(s) == ThreadSafe {
+		return result
+	}
+	u := newNonTS[U]()
+	u.m = result.m
+	return u
+}
+
+// HashFunc is like the Hash method, but hashes each item with the given
+// function instead of its formatted representation. hash must return the same
+// value for equal items.
+func HashFunc[T comparable](s Set[T], hash func(T) uint64) uint64 {
+	var sum uint64
+	orEmpty(s).Each(func(item T) bool {
+		sum += hash(item)
+		return true
+	})
+	return sum
+}
+
+// Frequency returns, for each item in any of the given sets, the number of
+// sets which contain it.
+func Frequency[T comparable](sets ...Set[T]) map[T]int {
+	freq := make(map[T]int)
+	for _, set := range orEmptyAll(sets...) {
+		set.Each(func(item T) bool {
+			freq[item]++
+			return true
+		})
+	}
+	return freq
+}
+
+// CommonToAtLeast returns a new set which contains the items that exist in at
+// least k of the given sets. With k equal to the number of sets this is their
+// Intersection, and with k of one (or less) it's their Union. If k exceeds the
+// number of sets the result is empty.
+//
+// The dynamic type of the returned set is determined by the first passed set.
+// Without any sets, an empty non-thread-safe set is returned.
+func CommonToAtLeast[T comparable](k int, sets ...Set[T]) Set[T] {
+	var result Set[T] = newNonTS[T]()
+	if len(sets) > 0 {
+		result = newLike(sets[0])
+	}
+
+	for item, n := range Frequency(sets...) {
+		if n >= k {
+			result.Add(item)
+		}
+	}
+	return result
+}
+
+// EqualApprox reports whether a and b are equal when items within epsilon of
+// each other are considered equal. The sets must have the same size, and their
+// items are paired greedily in ascending order: the i-th smallest item of a
+// with the i-th smallest item of b. Each item needs a counterpart of its own,
+// so near-duplicates within one set, like 1.0 and 1.0001, can't both match a
+// single item of the other. As closeness isn't transitive, EqualApprox is not
+// an equivalence relation. NaN is never equal to anything.
+func EqualApprox(a, b Set[float64], epsilon float64) bool {
+	as, bs := orEmpty(a).List(), orEmpty(b).List()
+	if len(as) != len(bs) {
+		return false
+	}
+
+	sort.Float64s(as)
+	sort.Float64s(bs)
+
+	for i := range as {
+		if d := math.Abs(as[i] - bs[i]); !(d <= epsilon) { // also catches NaN
+			return false
+		}
+	}
+	return true
+}
+
+// AddRange adds every integer in [lo, hi] to s, locking a thread-safe s only
+// once. If lo > hi it does nothing. It takes time and memory proportional to
+// hi-lo, so beware of huge ranges.
+func AddRange(s Set[int], lo, hi int) {
+	mustNotBeNil("AddRange", "s", s)
+	if lo > hi {
+		return
+	}
+
+	add := func(s Set[int]) {
+		for i := lo; ; i++ {
+			s.Add(i)
+			if i == hi { // not i <= hi, which overflows for hi == math.MaxInt
+				break
+			}
+		}
+	}
+
+	addingTo(s, add)
+}
+
+// RemoveRange removes every integer in [lo, hi] from s, locking a thread-safe
+// s only once. If lo > hi it does nothing. It takes time proportional to
+// hi-lo, so beware of huge ranges.
+func RemoveRange(s Set[int], lo, hi int) {
+	mustNotBeNil("RemoveRange", "s", s)
+	if lo > hi {
+		return
+	}
+
+	s, unlock := writeLocked(s)
+	defer unlock()
+
+	for i := lo; ; i++ {
+		s.Remove(i)
+		if i == hi {
+			break
+		}
+	}
+}
+
+// CountInRange returns the number of items of s in [lo, hi]. It iterates
+// whichever is smaller of the range and s.
+func CountInRange(s Set[int], lo, hi int) int {
+	if lo > hi {
+		return 0
+	}
+
+	s, unlock := readLocked(s)
+	defer unlock()
+
+	count := 0
+	if span := uint64(hi) - uint64(lo); span < uint64(s.Size()) {
+		for i := lo; ; i++ {
+			if s.Has(i) {
+				count++
+			}
+			if i == hi {
+				break
+			}
+		}
+		return count
+	}
+
+	s.Each(func(item int) bool {
+		if lo <= item && item <= hi {
+			count++
+		}
+		return true
+	})
+	return count
+}
+
+// HasAllInRange reports whether every integer in [lo, hi] is in s. It's true
+// for an empty range, i.e. if lo > hi.
+func HasAllInRange(s Set[int], lo, hi int) bool {
+	if lo > hi {
+		return true
+	}
+
+	// s can't hold them all if the range is larger than s
+	if uint64(hi)-uint64(lo) >= uint64(orEmpty(s).Size()) {
+		return false
+	}
+	return CountInRange(s, lo, hi) == hi-lo+1
+}
+
+// IntersectsAtLeast reports whether a and b have at least k items in common.
+// It iterates the smaller set and stops as soon as k matches are found,
+// without building the intersection. It's true for k <= 0.
+func IntersectsAtLeast[T comparable](a, b Set[T], k int) bool {
+	if k <= 0 {
+		return true
+	}
+	if a == b {
+		return orEmpty(a).Size() >= k
+	}
+
+	a, b, unlock := readLockedTwo(a, b)
+	defer unlock()
+
+	if a.Size() < k || b.Size() < k {
+		return false
+	}
+	if b.Size() < a.Size() {
+		a, b = b, a
+	}
+
+	count := 0
+	a.Each(func(item T) bool {
+		if b.Has(item) {
+			count++
+		}
+		return count < k
+	})
+	return count >= k
+}
+
+// Number is a constraint permitting any integer or floating-point type.
+type Number interface {
+	~int | ~int8 | ~int16 | ~int32 | ~int64 |
+		~uint | ~uint8 | ~uint16 | ~uint32 | ~uint64 | ~uintptr |
+		~float32 | ~float64
+}
+
+// Sum returns the sum of the items of s, or zero if s is empty. It follows
+// the overflow and rounding rules of T; float sums depend on the iteration
+// order, so they may differ slightly between calls.
+func Sum[T Number](s Set[T]) T {
+	s, unlock := readLocked(s)
+	defer unlock()
+
+	var sum T
+	s.Each(func(item T) bool {
+		sum += item
+		return true
+	})
+	return sum
+}
+
+// Mean returns the arithmetic mean of the items of s, and false if s is
+// empty. The items are summed as float64, so integer sets don't overflow but
+// large integers may lose precision.
+func Mean[T Number](s Set[T]) (float64, bool) {
+	s, unlock := readLocked(s)
+	defer unlock()
+
+	if s.IsEmpty() {
+		return 0, false
+	}
+
+	var sum float64
+	s.Each(func(item T) bool {
+		sum += float64(item)
+		return true
+	})
+	return sum / float64(s.Size()), true
+}
+
+// MinMax returns the smallest and largest items of s in a single pass, and
+// false if s is empty. Items are ordered as by cmp.Less, so a NaN is the
+// smallest of floats.
+func MinMax[T cmp.Ordered](s Set[T]) (lo, hi T, ok bool) {
+	s, unlock := readLocked(s)
+	defer unlock()
+
+	s.Each(func(item T) bool {
+		if !ok {
+			lo, hi, ok = item, item, true
+			return true
+		}
+		if cmp.Less(item, lo) {
+			lo = item
+		}
+		if cmp.Less(hi, item) {
+			hi = item
+		}
+		return true
+	})
+	return lo, hi, ok
+}
+
+// SetComparison is the result of Compare.
+type SetComparison[T comparable] struct {
+	OnlyInA Set[T] // items of a which are not in b
+	OnlyInB Set[T] // items of b which are not in a
+	InBoth  Set[T] // items of both a and b
+}
+
+// Compare splits the items of a and b into those only in a, those only in b
+// and those in both, scanning each set once under its read lock. It's
+// equivalent to, but cheaper than, calling Difference both ways and
+// Intersection.
+//
+// The dynamic type of the returned sets is determined by a.
+func Compare[T comparable](a, b Set[T]) SetComparison[T] {
+	c := SetComparison[T]{newLike(a), newLike(a), newLike(a)}
+	compareInto(a, b, c.OnlyInA, c.OnlyInB, c.InBoth)
+	return c
+}
+
+// SymmetricParts returns the items only in a and those only in b, which
+// together form their SymmetricDifference, scanning each set once under its
+// read lock. Unlike SymmetricDifference, it tells which side each item came
+// from.
+//
+// The dynamic type of the returned sets is determined by a.
+func SymmetricParts[T comparable](a, b Set[T]) (onlyA, onlyB Set[T]) {
+	onlyA, onlyB = newLike(a), newLike(a)
+	compareInto(a, b, onlyA, onlyB, nil)
+	return onlyA, onlyB
+}
+
+// compareInto adds the items only in a to onlyA, those only in b to onlyB,
+// and those in both to inBoth, unless it's nil.
+func compareInto[T comparable](a, b, onlyA, onlyB, inBoth Set[T]) {
+	if a == b {
+		if inBoth != nil {
+			inBoth.Merge(a)
+		}
+		return
+	}
+
+	a, b, unlock := readLockedTwo(a, b)
+	defer unlock()
+
+	a.Each(func(item T) bool {
+		if !b.Has(item) {
+			onlyA.Add(item)
+		} else if inBoth != nil {
+			inBoth.Add(item)
+		}
+		return true
+	})
+	b.Each(func(item T) bool {
+		if !a.Has(item) {
+			onlyB.Add(item)
+		}
+		return true
+	})
+}
+
+// LazyUnion returns a sequence of the distinct items of all sets, without
+// building their union. The sets are traversed in turn, as by Keys, when the
+// sequence is ranged over, so a thread-safe set is read-locked while its
+// items are yielded. Duplicates are tracked in a map as it goes, so it still
+// takes memory proportional to the size of the union, but not a Set.
+func LazyUnion[T comparable](sets ...Set[T]) iter.Seq[T] {
+	return func(yield func(T) bool) {
+		seen := make(map[T]struct{})
+		for _, set := range orEmptyAll(sets...) {
+			for item := range set.Keys() {
+				if _, ok := seen[item]; ok {
+					continue
+				}
+				seen[item] = keyExists
+				if !yield(item) {
+					return
+				}
+			}
+		}
+	}
+}
+
+// LazyDifference returns a sequence of the items of a which are in none of
+// others, without building their difference. The sets are read-locked, in
+// the order described at lockTwo, while the sequence is ranged over, so the
+// loop body must not modify any of them.
+func LazyDifference[T comparable](a Set[T], others ...Set[T]) iter.Seq[T] {
+	return func(yield func(T) bool) {
+		if a == nil || slices.Contains(others, a) {
+			return // a has nothing which isn't in a
+		}
+
+		locks := make([]RWLockable, 0, len(others)+1)
+		views := make([]Set[T], 0, len(others))
+		for _, other := range others {
+			l, view := lockerOf(other)
+			locks, views = append(locks, l), append(views, view)
+		}
+		l, viewA := lockerOf(a)
+		unlock := rLockAll(append(locks, l)...)
+		defer unlock()
+
+		for item := range viewA.Keys() {
+			if !slices.ContainsFunc(views, func(view Set[T]) bool { return view.Has(item) }) && !yield(item) {
+				return
+			}
+		}
+	}
+}
+
+// ErrUncomparable is returned by SafeAdd for an item which can't be added to a
+// set, as it isn't comparable.
+var ErrUncomparable = errors.New("set: item is not comparable")
+
+// SafeAdd is like s.Add, but returns an error wrapping ErrUncomparable instead
+// of panicking if any of items isn't comparable, like a slice, map or func, or
+// a struct or array holding one. In that case none of the items is added.
+// Each item is checked by reflection first, which costs far more than adding
+// it, so use Add for items known to be comparable.
+func SafeAdd(s Set[any], items ...any) error {
+	mustNotBeNil("SafeAdd", "s", s)
+
+	for i, item := range items {
+		if item != nil && !reflect.ValueOf(item).Comparable() {
+			return fmt.Errorf("%w: item %d of type %T", ErrUncomparable, i, item)
+		}
+	}
+	s.Add(items...)
+	return nil
+}
+
+// IsHomogeneous reports whether all items of s share the same dynamic type,
+// and returns that type, e.g. to catch items of different types mixed up in a
+// Set[any]. A nil item has a type of its own, which is returned as nil. An
+// empty or nil set is homogeneous, with a nil type. The items are examined by
+// reflection, one by one under the read lock of s, stopping at the first
+// mismatch, which costs far more than traversing s.
+func IsHomogeneous[T comparable](s Set[T]) (reflect.Type, bool) {
+	s, unlock := readLocked(s)
+	defer unlock()
+
+	var typ reflect.Type
+	first, same := true, true
+	s.Each(func(item T) bool {
+		t := reflect.TypeOf(any(item))
+		if first {
+			typ, first = t, false
+		}
+		same = t == typ
+		return same
+	})
+	if !same {
+		return nil, false
+	}
+	return typ, true
+}
+
+// EqualBy reports whether key maps the items of a one-to-one onto the items of
+// b, e.g. to compare a set of structs against a set of their IDs. It's false
+// if key maps distinct items of a to the same item, even if the mapped items
+// make up b. Both sets are read-locked, in the order described at lockTwo.
+func EqualBy[T, U comparable](a Set[T], b Set[U], key func(T) U) bool {
+	la, viewA := lockerOf(a)
+	lb, viewB := lockerOf(b)
+	unlock := rLockTwo(la, lb)
+	defer unlock()
+
+	if viewA.Size() != viewB.Size() {
+		return false
+	}
+
+	seen := make(map[U]struct{}, viewA.Size())
+	equal := true
+	viewA.Each(func(item T) bool {
+		k := key(item)
+		if _, dup := seen[k]; dup || !viewB.Has(k) {
+			equal = false
+			return false
+		}
+		seen[k] = keyExists
+		return true
+	})
+	return equal
+}
+
+// EqualCanonical reports whether a and b hold the same items once each item is
+// replaced by canon(item), e.g. strings.ToLower to compare string sets case
+// insensitively. The canonical forms are compared as sets, so items which
+// collide there count once, and sets of different sizes may be equal: {"a",
+// "A"} is equal to {"a"} under strings.ToLower. Both sets are read-locked, in
+// the order described at lockTwo.
+func EqualCanonical[T comparable](a, b Set[T], canon func(T) T) bool {
+	a, b, unlock := readLockedTwo(a, b)
+	defer unlock()
+
+	ca := make(map[T]struct{}, a.Size())
+	a.Each(func(item T) bool {
+		ca[canon(item)] = keyExists
+		return true
+	})
+
+	cb := make(map[T]struct{}, len(ca))
+	equal := true
+	b.Each(func(item T) bool {
+		c := canon(item)
+		if _, ok := ca[c]; !ok {
+			equal = false
+			return false
+		}
+		cb[c] = keyExists
+		return true
+	})
+	return equal && len(cb) == len(ca)
+}
+
+// IntersectionWhere returns a new set of the type of a, which contains the
+// items in both a and b for which pred returns true. It's a single pass over
+// the smaller of the sets, without building their intersection first. Both
+// sets are read-locked, in the order described at lockTwo, while pred is
+// called, so pred must not modify either of them.
+func IntersectionWhere[T comparable](a, b Set[T], pred func(T) bool) Set[T] {
+	result := newLike(a)
+
+	viewA, viewB, unlock := readLockedTwo(a, b)
+	defer unlock()
+
+	small, large := viewA, viewB
+	if large.Size() < small.Size() {
+		small, large = large, small
+	}
+
+	var items []T
+	small.Each(func(item T) bool {
+		if large.Has(item) && pred(item) {
+			items = append(items, item)
+		}
+		return true
+	})
+	result.Add(items...)
+	return result
+}
+
+// MergeMapped adds f(item) to dst for every item of src, without building a
+// set of the mapped items first, e.g. to merge relative paths into a set of
+// absolute ones. Items f maps to the same one, or to one already in dst, are
+// added once. src is snapshotted under its read lock first, and f is called
+// with no lock held, so a thread-safe dst is then write-locked once to add the
+// mapped items. A nil src is treated as empty.
+func MergeMapped[T comparable](dst, src Set[T], f func(T) T) {
+	mustNotBeNil("MergeMapped", "dst", dst)
+
+	items := orEmpty(src).List()
+	for i, item := range items {
+		items[i] = f(item)
+	}
+	dst.Add(items...)
+}
+
+// ReadFrom replaces the items of s with those read from r, one per line, as
+// written by WriteTo. Each line is parsed into an item by parse, with its line
+// ending, "\n" or "\r\n", removed; a last line without one is read as well.
+// It returns the number of bytes consumed from r, and the first error reading
+// r or parsing a line, in which case s is left unmodified. A thread-safe s is
+// replaced under a single write lock, so concurrent readers see either its old
+// or its new items. For a Set[string], parse may be ParseString.
+//
+// It's a function rather than an io.ReaderFrom method, as a method of Set[T]
+// couldn't parse items of any T.
+func ReadFrom[T comparable](s Set[T], r io.Reader, parse func(line string) (T, error)) (int64, error) {
+	mustNotBeNil("ReadFrom", "s", s)
+
+	var n int64
+	var items []T
+	br := bufio.NewReader(r)
+	for lineNo := 1; ; lineNo++ {
+		line, err := br.ReadString('\n')
+		n += int64(len(line))
+		if err != nil && err != io.EOF {
+			return n, err
+		}
+		if line == "" {
+			break // EOF right after the last line ending
+		}
+
+		item, perr := parse(strings.TrimSuffix(strings.TrimSuffix(line, "\n"), "\r"))
+		if perr != nil {
+			return n, fmt.Errorf("set: line %d: %w", lineNo, perr)
+		}
+		items = append(items, item)
+
+		if err == io.EOF {
+			break
+		}
+	}
+
+	addingTo(s, func(s Set[T]) {
+		s.Clear()
+		s.AddAll(items...)
+	})
+	return n, nil
+}
+
+// ParseString returns line itself, to pass to ReadFrom for a Set[string].
+func ParseString(line string) (string, error) {
+	return line, nil
+}
+
+// LengthHistogram returns the number of items of s of each length, in bytes
+// as by len, e.g. to profile a set of tokens. Lengths no item has are left
+// out, so the map is empty for an empty or nil set.
+func LengthHistogram(s Set[string]) map[int]int {
+	s, unlock := readLocked(s)
+	defer unlock()
+
+	hist := make(map[int]int)
+	s.Each(func(item string) bool {
+		hist[len(item)]++
+		return true
+	})
+	return hist
+}
+
+// WithPrefix returns a new set of the type of s, which contains the items of s
+// starting with prefix, e.g. to pick the keys under a path.
+func WithPrefix(s Set[string], prefix string) Set[string] {
+	return matching(s, func(item string) bool { return strings.HasPrefix(item, prefix) })
+}
+
+// WithSuffix returns a new set of the type of s, which contains the items of s
+// ending with suffix, e.g. to pick the file names with an extension.
+func WithSuffix(s Set[string], suffix string) Set[string] {
+	return matching(s, func(item string) bool { return strings.HasSuffix(item, suffix) })
+}
+
+// MatchingRegexp returns a new set of the type of s, which contains the items
+// of s re matches. As re.MatchString looks for a match anywhere in an item,
+// anchor re with ^ and $ to match whole items.
+func MatchingRegexp(s Set[string], re *regexp.Regexp) Set[string] {
+	return matching(s, re.MatchString)
+}
+
+// matching returns a new set of the type of s, which contains the items of s
+// for which match returns true. s is read-locked during the scan.
+func matching(s Set[string], match func(string) bool) Set[string] {
+	result := newLike(s)
+
+	s, unlock := readLocked(s)
+	defer unlock()
+
+	var items []string
+	s.Each(func(item string) bool {
+		if match(item) {
+			items = append(items, item)
+		}
+		return true
+	})
+	result.Add(items...)
+	return result
+}
+
+// Buckets groups the items of s into buckets of width consecutive integers,
+// keyed by their lower bound, e.g. to build a histogram. The bounds are
+// multiples of width, as by floor division, so with a width of 10, -1 falls
+// in the bucket of -10 and 5 in that of 0. The lowest bucket is keyed by
+// math.MinInt if its lower bound is below it. The items of each bucket are
+// sorted, and empty buckets are left out. It returns nil if width <= 0.
+func Buckets(s Set[int], width int) map[int][]int {
+	if width <= 0 {
+		return nil
+	}
+
+	s, unlock := readLocked(s)
+	defer unlock()
+
+	buckets := make(map[int][]int)
+	s.Each(func(item int) bool {
+		m := item % width
+		if m < 0 {
+			m += width
+		}
+		lo := item - m
+		if lo > item { // wrapped around below math.MinInt
+			lo = math.MinInt
+		}
+		buckets[lo] = append(buckets[lo], item)
+		return true
+	})
+	for _, items := range buckets {
+		slices.Sort(items)
+	}
+	return buckets
+}
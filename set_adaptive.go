@@ -0,0 +1,658 @@
+package set
+
+import (
+	"cmp"
+	"io"
+	"iter"
+	"sync"
+)
+
+// adaptiveLimit is the size beyond which a SetAdaptive is promoted from a
+// sorted slice to a map.
+const adaptiveLimit = 32
+
+// SetAdaptive defines a set data structure which starts out backed by a sorted
+// slice, like SetSmall, and is promoted to a map, like SetNonTS, once it holds
+// more than 32 items. Promotion is transparent and permanent: the set isn't
+// demoted when it shrinks again. Each call is forwarded to the backing set,
+// which costs a few nanoseconds over using it directly; see
+// BenchmarkAdaptive. NewAdaptive creates either a thread-safe or a
+// non-thread-safe one.
+//
+// Like SetTS, the thread-safe one holds its read lock while calling the
+// closure passed to Each and similar methods, which therefore must not modify
+// it.
+type SetAdaptive[T cmp.Ordered] struct {
+	l    *sync.RWMutex // nil unless thread-safe
+	st   *adaptiveState[T]
+	view bool // an unlocked view, leaving thresholds to its owner
+}
+
+// adaptiveState is the state of a SetAdaptive, shared with its unlocked views.
+type adaptiveState[T cmp.Ordered] struct {
+	inner      Set[T]          // a *SetSmall until promoted, then a *SetNonTS
+	thresholds []sizeThreshold // registered by OnSizeThreshold
+}
+
+// NewAdaptive creates and initializes a new Set backed by a sorted slice
+// while small, and by a map once it grows beyond 32 items. See SetAdaptive.
+func NewAdaptive[T cmp.Ordered](setType SetType) Set[T] {
+	return newAdaptive[T](setType == ThreadSafe, newSmall[T]())
+}
+
+// newAdaptive creates a new SetAdaptive backed by inner, which it takes over.
+func newAdaptive[T cmp.Ordered](threadSafe bool, inner Set[T]) *SetAdaptive[T] {
+	s := &SetAdaptive[T]{st: &adaptiveState[T]{inner: inner}}
+	if threadSafe {
+		s.l = &sync.RWMutex{}
+	}
+	s.promote()
+
+	// Ensure interface compliance
+	var _ Set[T] = s
+
+	return s
+}
+
+// like returns a new SetAdaptive of the same thread safety as s, backed by
+// inner.
+func (s *SetAdaptive[T]) like(inner Set[T]) *SetAdaptive[T] {
+	return newAdaptive(s.l != nil, inner)
+}
+
+// NewEmpty returns a new empty adaptive Set of the same thread safety.
+func (s *SetAdaptive[T]) NewEmpty() Set[T] {
+	return s.like(newSmall[T]())
+}
+
+// promote replaces a sorted slice backing s by a map once it's too large.
+// s must be write-locked.
+func (s *SetAdaptive[T]) promote() {
+	if small, ok := s.st.inner.(*SetSmall[T]); ok && small.Size() > adaptiveLimit {
+		s.st.inner = small.AsNonThreadSafe()
+	}
+}
+
+// rlock read-locks s if it's thread-safe.
+func (s *SetAdaptive[T]) rlock() {
+	if s.l != nil {
+		s.l.RLock()
+	}
+}
+
+// runlock releases the read lock taken by rlock.
+func (s *SetAdaptive[T]) runlock() {
+	if s.l != nil {
+		s.l.RUnlock()
+	}
+}
+
+// lock write-locks s if it's thread-safe.
+func (s *SetAdaptive[T]) lock() {
+	if s.l != nil {
+		s.l.Lock()
+	}
+}
+
+// unlock releases the write lock taken by lock.
+func (s *SetAdaptive[T]) unlock() {
+	if s.l != nil {
+		s.l.Unlock()
+	}
+}
+
+func (s *SetAdaptive[T]) lockedView() (RWLockable, Set[T]) {
+	if s.l == nil {
+		return nil, s
+	}
+	return s.l, &SetAdaptive[T]{st: s.st, view: true}
+}
+
+// addingView calls f with an unlocked view of s, which adds items to it, like
+// adding does.
+func (s *SetAdaptive[T]) addingView(f func(Set[T])) {
+	s.adding(func() { f(&SetAdaptive[T]{st: s.st, view: true}) })
+}
+
+func (s *SetAdaptive[T]) threadSafe() bool {
+	return s.l != nil
+}
+
+// rLockWith read-locks s along with t, if it's lockable, in the order
+// described at lockTwo. It returns the comparisons of the set backing s, a
+// view of t to pass to them, and the function releasing the locks.
+func (s *SetAdaptive[T]) rLockWith(t Set[T]) (comparer[T], Set[T], func()) {
+	var own RWLockable
+	if s.l != nil {
+		own = s.l
+	}
+
+	l, view := lockerOf(t)
+	unlock := rLockTwo(own, l)
+	return s.st.inner.(comparer[T]), view, unlock
+}
+
+// adding calls f, which adds items to s, under the write lock, and promotes s
+// if needed. The callbacks registered by OnSizeThreshold whose threshold f
+// crossed are called after the lock is released. A view only promotes itself,
+// as its owner holds the lock, and checks the thresholds once it's released.
+func (s *SetAdaptive[T]) adding(f func()) {
+	if s.view {
+		f()
+		s.promote()
+		return
+	}
+
+	crossed, size := func() ([]func(int), int) {
+		s.lock()
+		defer s.unlock()
+
+		before := s.st.inner.Size()
+		f()
+		s.promote()
+		after := s.st.inner.Size()
+		return thresholdsCrossed(s.st.thresholds, before, after), after
+	}()
+	notify(crossed, size)
+}
+
+// Add includes the specified items (one or more) to the set. The underlying
+// Set s is modified. If passed nothing it silently returns.
+func (s *SetAdaptive[T]) Add(items ...T) {
+	s.adding(func() { s.st.inner.Add(items...) })
+}
+
+// AddAll includes the specified items to the set, like Add, but first grows
+// the set to hold all of them.
+func (s *SetAdaptive[T]) AddAll(items ...T) {
+	s.adding(func() { s.st.inner.AddAll(items...) })
+}
+
+// AddNew includes the specified items to the set, like Add, and returns a new
+// set of those which weren't already present. An item passed more than once
+// is new only the first time.
+func (s *SetAdaptive[T]) AddNew(items ...T) (added Set[T]) {
+	s.adding(func() { added = s.like(s.st.inner.AddNew(items...)) })
+	return added
+}
+
+// Intern adds item to the set if it isn't present yet, and returns the stored
+// item of the set equal to it. See set.Intern for details.
+func (s *SetAdaptive[T]) Intern(item T) (stored T) {
+	s.adding(func() { stored = s.st.inner.Intern(item) })
+	return stored
+}
+
+// Toggle removes each of the specified items which is in the set, and adds
+// each one which isn't. See set.Toggle for details.
+func (s *SetAdaptive[T]) Toggle(items ...T) {
+	s.adding(func() { s.st.inner.Toggle(items...) })
+}
+
+// ToggleReport is like Toggle, and returns whether each item is in the set
+// after toggling it, in the same order as the items.
+func (s *SetAdaptive[T]) ToggleReport(items ...T) (has []bool) {
+	s.adding(func() { has = s.st.inner.ToggleReport(items...) })
+	return has
+}
+
+// Remove deletes the specified items from the set.  The underlying Set s is
+// modified. If passed nothing it silently returns.
+func (s *SetAdaptive[T]) Remove(items ...T) {
+	s.lock()
+	defer s.unlock()
+
+	s.st.inner.Remove(items...)
+}
+
+// RemoveReturning deletes the specified items from the set, like Remove, and
+// returns a new set of those which were actually present and removed.
+func (s *SetAdaptive[T]) RemoveReturning(items ...T) Set[T] {
+	s.lock()
+	defer s.unlock()
+
+	return s.like(s.st.inner.RemoveReturning(items...))
+}
+
+// RemoveReport deletes the specified items from the set, like Remove, and
+// returns those which were removed and those which weren't present. See
+// set.RemoveReport for details.
+func (s *SetAdaptive[T]) RemoveReport(items ...T) (removed, notFound []T) {
+	s.lock()
+	defer s.unlock()
+
+	return s.st.inner.RemoveReport(items...)
+}
+
+// Pop  deletes and return an item from the set. The underlying Set s is
+// modified. If set is empty, the zero value and false are returned.
+func (s *SetAdaptive[T]) Pop() (T, bool) {
+	s.lock()
+	defer s.unlock()
+
+	return s.st.inner.Pop()
+}
+
+// PopHint deletes and returns hint if it's in the set, and otherwise an
+// arbitrary item like Pop. Only if the set is empty, the zero value and false
+// are returned.
+func (s *SetAdaptive[T]) PopHint(hint T) (T, bool) {
+	s.lock()
+	defer s.unlock()
+
+	return s.st.inner.PopHint(hint)
+}
+
+// PopWhere deletes every item of the set for which pred returns true, and
+// returns them as a new set. The write lock of a thread-safe set is held for
+// the whole operation, so pred must not call methods of s.
+func (s *SetAdaptive[T]) PopWhere(pred func(T) bool) Set[T] {
+	s.lock()
+	defer s.unlock()
+
+	return s.like(s.st.inner.PopWhere(pred))
+}
+
+// Has looks for the existence of items passed. It returns false if nothing is
+// passed. For multiple items it returns true only if all of  the items exist.
+func (s *SetAdaptive[T]) Has(items ...T) bool {
+	s.rlock()
+	defer s.runlock()
+
+	return s.st.inner.Has(items...)
+}
+
+// HasAll is an explicit alias of Has. It returns true only if all of the
+// passed items exist, and false if nothing is passed.
+func (s *SetAdaptive[T]) HasAll(items ...T) bool {
+	return s.Has(items...)
+}
+
+// HasAny looks for the existence of items passed. It returns false if nothing
+// is passed. For multiple items it returns true if at least one of the items
+// exists.
+func (s *SetAdaptive[T]) HasAny(items ...T) bool {
+	s.rlock()
+	defer s.runlock()
+
+	return s.st.inner.HasAny(items...)
+}
+
+// HasEach looks for the existence of each item passed. It returns a slice
+// reporting the membership of each item, in the same order as the items.
+func (s *SetAdaptive[T]) HasEach(items ...T) []bool {
+	s.rlock()
+	defer s.runlock()
+
+	return s.st.inner.HasEach(items...)
+}
+
+// Get looks up item, returning the stored item of the set equal to it and
+// true, or the zero value and false. See set.Get for details.
+func (s *SetAdaptive[T]) Get(item T) (T, bool) {
+	s.rlock()
+	defer s.runlock()
+
+	return s.st.inner.Get(item)
+}
+
+// Size returns the number of items in a set.
+func (s *SetAdaptive[T]) Size() int {
+	s.rlock()
+	defer s.runlock()
+
+	return s.st.inner.Size()
+}
+
+// OnSizeThreshold registers f to be called whenever adding items grows the
+// set from less than n items to n or more. See set.OnSizeThreshold for
+// details. f is called after the write lock is released, so it may call any
+// method of s.
+func (s *SetAdaptive[T]) OnSizeThreshold(n int, f func(size int)) {
+	s.lock()
+	defer s.unlock()
+
+	s.st.thresholds = append(s.st.thresholds, sizeThreshold{n, f})
+}
+
+// Cap returns a best-effort estimate of the number of items the set can hold
+// without growing its backing slice or map.
+func (s *SetAdaptive[T]) Cap() int {
+	s.rlock()
+	defer s.runlock()
+
+	return s.st.inner.Cap()
+}
+
+// Grow ensures the set can hold n more items without growing its backing
+// slice or map. It promotes the set if it would hold more than 32 items.
+func (s *SetAdaptive[T]) Grow(n int) {
+	s.lock()
+	defer s.unlock()
+
+	small, ok := s.st.inner.(*SetSmall[T])
+	if ok && small.Size()+n > adaptiveLimit {
+		s.st.inner = small.AsNonThreadSafe()
+	}
+	s.st.inner.Grow(n)
+}
+
+// EstimatedBytes returns a rough estimate of the heap size of the set's
+// backing slice or map.
+func (s *SetAdaptive[T]) EstimatedBytes() int {
+	s.rlock()
+	defer s.runlock()
+
+	return s.st.inner.EstimatedBytes()
+}
+
+// Clear removes all items from the set. A promoted set stays backed by a
+// map.
+func (s *SetAdaptive[T]) Clear() {
+	s.lock()
+	defer s.unlock()
+
+	s.st.inner.Clear()
+}
+
+// Drain removes all items from the set, like Clear, and returns them in
+// ascending order while the set is small, and in an unspecified order
+// otherwise. If the set is empty, an empty slice is returned.
+func (s *SetAdaptive[T]) Drain() []T {
+	s.lock()
+	defer s.unlock()
+
+	return s.st.inner.Drain()
+}
+
+// Compact rebuilds the backing slice or map at the current size.
+func (s *SetAdaptive[T]) Compact() {
+	s.lock()
+	defer s.unlock()
+
+	s.st.inner.Compact()
+}
+
+// NormalizeInPlace replaces every item of the set with f(item), collapsing
+// items which f maps to the same one. See set.NormalizeInPlace for details.
+func (s *SetAdaptive[T]) NormalizeInPlace(f func(item T) T) {
+	s.lock()
+	defer s.unlock()
+
+	s.st.inner.NormalizeInPlace(f)
+}
+
+// IsEmpty reports whether the Set is empty.
+func (s *SetAdaptive[T]) IsEmpty() bool {
+	return s.Size() == 0
+}
+
+// IsEqual test whether s and t are the same in size and have the same items.
+// If t is lockable it's read-locked as well, in the order described at
+// lockTwo.
+func (s *SetAdaptive[T]) IsEqual(t Set[T]) bool {
+	inner, view, unlock := s.rLockWith(t)
+	defer unlock()
+
+	return inner.isEqual(view)
+}
+
+// IsSubset tests whether t is a subset of s.
+func (s *SetAdaptive[T]) IsSubset(t Set[T]) bool {
+	inner, view, unlock := s.rLockWith(t)
+	defer unlock()
+
+	return inner.isSubset(view)
+}
+
+// IsSuperset tests whether t is a superset of s.
+func (s *SetAdaptive[T]) IsSuperset(t Set[T]) bool {
+	return orEmpty(t).IsSubset(s)
+}
+
+// ContainsSet tests whether every item of t is in s. It's the same as
+// s.IsSubset(t), which reads less naturally.
+func (s *SetAdaptive[T]) ContainsSet(t Set[T]) bool {
+	return s.IsSubset(t)
+}
+
+// ContainsAnySet tests whether any item of t is in s.
+func (s *SetAdaptive[T]) ContainsAnySet(t Set[T]) bool {
+	inner, view, unlock := s.rLockWith(t)
+	defer unlock()
+
+	return inner.containsAnySet(view)
+}
+
+// Each traverses the items in the Set, calling the provided function for each
+// set member. Traversal will continue until all items in the Set have been
+// visited, or if the closure returns false.
+func (s *SetAdaptive[T]) Each(f func(item T) bool) {
+	s.rlock()
+	defer s.runlock()
+
+	s.st.inner.Each(f)
+}
+
+// EachSnapshot is like Each, but traverses a snapshot of the items taken at
+// call time, without holding the lock while calling the closure, so the
+// closure may modify the set.
+func (s *SetAdaptive[T]) EachSnapshot(f func(item T) bool) {
+	eachOf(s.List(), f)
+}
+
+// EachErr traverses the items in the Set, calling the provided function for
+// each set member. Traversal stops at the first error returned by the closure,
+// which is then returned. A nil error means all items have been visited.
+func (s *SetAdaptive[T]) EachErr(f func(item T) error) error {
+	s.rlock()
+	defer s.runlock()
+
+	return s.st.inner.EachErr(f)
+}
+
+// EachIndexed is like Each, but also passes a running index, starting at
+// zero, to the closure.
+func (s *SetAdaptive[T]) EachIndexed(f func(i int, item T) bool) {
+	s.rlock()
+	defer s.runlock()
+
+	s.st.inner.EachIndexed(f)
+}
+
+// EachBatch traverses the items in the Set like Each, but passes them to the
+// closure in batches of up to batchSize items. See set.EachBatch for details.
+func (s *SetAdaptive[T]) EachBatch(batchSize int, f func(batch []T) bool) {
+	s.rlock()
+	defer s.runlock()
+
+	s.st.inner.EachBatch(batchSize, f)
+}
+
+// EachParallel calls f for each item of a snapshot of the set from workers
+// goroutines at once, without holding the lock. See set.EachParallel for
+// details.
+func (s *SetAdaptive[T]) EachParallel(workers int, f func(item T)) {
+	eachParallel(s.List(), workers, f)
+}
+
+// Iterator returns an iterator over a snapshot of the items of the set, taken
+// at call time.
+func (s *SetAdaptive[T]) Iterator() *Iterator[T] {
+	return newIterator(s.List())
+}
+
+// Keys returns a sequence of the items of the set. It's a live view over the
+// set rather than a copy; ranging over a thread-safe set holds the read lock
+// for the whole loop, so the loop body must not modify s.
+func (s *SetAdaptive[T]) Keys() iter.Seq[T] {
+	return func(yield func(T) bool) {
+		s.Each(yield)
+	}
+}
+
+// String returns a string representation of s
+func (s *SetAdaptive[T]) String() string {
+	s.rlock()
+	defer s.runlock()
+
+	return s.st.inner.String()
+}
+
+// StringFunc returns a string representation of s, formatting each item with
+// format and separating them with sep. Unlike String, the result isn't
+// enclosed in square brackets. If format is nil, items are formatted with %v.
+func (s *SetAdaptive[T]) StringFunc(sep string, format func(T) string) string {
+	s.rlock()
+	defer s.runlock()
+
+	return s.st.inner.StringFunc(sep, format)
+}
+
+// WriteTo writes the items of s to w, one per line. See set.WriteTo for
+// details. The items are snapshotted under the read lock first, and then
+// written with no lock held.
+func (s *SetAdaptive[T]) WriteTo(w io.Writer) (int64, error) {
+	return writeItems(w, s.List())
+}
+
+// Hash returns a hash of the items of s, which is independent of the order
+// they're traversed in. See set.Hash for details.
+func (s *SetAdaptive[T]) Hash() uint64 {
+	s.rlock()
+	defer s.runlock()
+
+	return s.st.inner.Hash()
+}
+
+// List returns a slice of all items.
+func (s *SetAdaptive[T]) List() []T {
+	s.rlock()
+	defer s.runlock()
+
+	return s.st.inner.List()
+}
+
+// Stream returns a channel over which all items of the set are sent, after
+// which the channel is closed. The items are a snapshot taken at call time.
+func (s *SetAdaptive[T]) Stream() <-chan T {
+	return stream(s.List())
+}
+
+// Tee returns n channels, over each of which all items of the set are sent,
+// after which the channels are closed. See set.Tee for details.
+func (s *SetAdaptive[T]) Tee(n int) []<-chan T {
+	return tee(s.List(), n)
+}
+
+// Copy returns a new Set with a copy of s, of the same thread safety.
+func (s *SetAdaptive[T]) Copy() Set[T] {
+	return s.CopyWithCap(0)
+}
+
+// CopyWithCap returns a new Set with a copy of s, like Copy, with room for
+// extra more items reserved. extra is a hint, not a limit.
+func (s *SetAdaptive[T]) CopyWithCap(extra int) Set[T] {
+	s.rlock()
+	defer s.runlock()
+
+	return s.like(s.st.inner.CopyWithCap(extra))
+}
+
+// Snapshot returns a read-only copy of s, which panics if modified. Unlike a
+// Copy, it's safe for concurrent reads without locking.
+func (s *SetAdaptive[T]) Snapshot() Set[T] {
+	s.rlock()
+	defer s.runlock()
+
+	return s.st.inner.Snapshot()
+}
+
+// SplitN partitions the items of s into n new sets of the same thread safety.
+// Each item ends up in exactly one of the sets, and their sizes differ by at
+// most one. If n is less than one, nil is returned.
+func (s *SetAdaptive[T]) SplitN(n int) []Set[T] {
+	s.rlock()
+	defer s.runlock()
+
+	sets := s.st.inner.SplitN(n)
+	for i, set := range sets {
+		sets[i] = s.like(set)
+	}
+	return sets
+}
+
+// AsThreadSafe returns a new thread-safe Set with a copy of s, backed by a
+// map.
+func (s *SetAdaptive[T]) AsThreadSafe() Set[T] {
+	s.rlock()
+	defer s.runlock()
+
+	return s.st.inner.AsThreadSafe()
+}
+
+// AsNonThreadSafe returns a new non-thread-safe Set with a copy of s, backed
+// by a map.
+func (s *SetAdaptive[T]) AsNonThreadSafe() Set[T] {
+	s.rlock()
+	defer s.runlock()
+
+	return s.st.inner.AsNonThreadSafe()
+}
+
+// Merge is like Union, however it modifies the current set it's applied on
+// with the given t set.
+func (s *SetAdaptive[T]) Merge(t Set[T]) {
+	if conv, ok := t.(*SetAdaptive[T]); ok && conv.st == s.st {
+		return // nothing to add
+	}
+	items := orEmpty(t).List() // don't lock t while s is
+	s.adding(func() { s.st.inner.Add(items...) })
+}
+
+// Separate removes the set items containing in t from set s. Please aware that
+// it's not the opposite of Merge.
+func (s *SetAdaptive[T]) Separate(t Set[T]) {
+	if conv, ok := t.(*SetAdaptive[T]); ok && conv.st == s.st {
+		s.Clear()
+		return
+	}
+	items := orEmpty(t).List() // don't lock t while s is
+	s.Remove(items...)
+}
+
+// RetainSlice removes the items of s which don't appear in items, leaving the
+// intersection of s and items.
+func (s *SetAdaptive[T]) RetainSlice(items []T) {
+	s.lock()
+	defer s.unlock()
+
+	s.st.inner.RetainSlice(items)
+}
+
+// DifferenceSlice returns a new set which contains the items of s which don't
+// appear in items.
+func (s *SetAdaptive[T]) DifferenceSlice(items []T) Set[T] {
+	s.rlock()
+	defer s.runlock()
+
+	return s.like(s.st.inner.DifferenceSlice(items))
+}
+
+// IsSubsetSlice tests whether every item of s appears in items. See
+// set.IsSubsetSlice for details.
+func (s *SetAdaptive[T]) IsSubsetSlice(items []T) bool {
+	s.rlock()
+	defer s.runlock()
+
+	return s.st.inner.IsSubsetSlice(items)
+}
+
+// IsSupersetSlice tests whether every one of items is in s. See
+// set.IsSupersetSlice for details.
+func (s *SetAdaptive[T]) IsSupersetSlice(items []T) bool {
+	s.rlock()
+	defer s.runlock()
+
+	return s.st.inner.IsSupersetSlice(items)
+}
@@ -0,0 +1,153 @@
+package set
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+)
+
+// adaptiveInner returns the set backing s.
+func adaptiveInner[T int](s Set[T]) Set[T] {
+	return s.(*SetAdaptive[T]).st.inner
+}
+
+func TestSetAdaptive_Promote(t *testing.T) {
+	for _, st := range []SetType{ThreadSafe, NonThreadSafe} {
+		s := NewAdaptive[int](st)
+		for i := 0; i < adaptiveLimit; i++ {
+			s.Add(i)
+		}
+		if _, ok := adaptiveInner(s).(*SetSmall[int]); !ok {
+			t.Errorf("Add: should be backed by a slice up to %d items, got %T", adaptiveLimit, adaptiveInner(s))
+		}
+
+		s.Add(adaptiveLimit)
+		if _, ok := adaptiveInner(s).(*SetNonTS[int]); !ok {
+			t.Errorf("Add: should be backed by a map beyond %d items, got %T", adaptiveLimit, adaptiveInner(s))
+		}
+
+		if s.Size() != adaptiveLimit+1 || !s.Has(0, adaptiveLimit) {
+			t.Error("Add: items should be retained on promotion, got", s)
+		}
+
+		s.Clear()
+		if _, ok := adaptiveInner(s).(*SetNonTS[int]); !ok || !s.IsEmpty() {
+			t.Error("Clear: a promoted set should stay backed by a map")
+		}
+	}
+}
+
+func TestSetAdaptive_Grow(t *testing.T) {
+	s := NewAdaptive[int](NonThreadSafe)
+	s.Add(1)
+	s.Grow(adaptiveLimit)
+
+	if _, ok := adaptiveInner(s).(*SetNonTS[int]); !ok || !s.Has(1) {
+		t.Errorf("Grow: should promote when growing beyond %d items, got %T", adaptiveLimit, adaptiveInner(s))
+	}
+}
+
+func TestSetAdaptive_SetType(t *testing.T) {
+	for _, st := range []SetType{ThreadSafe, NonThreadSafe} {
+		s := NewAdaptive[int](st)
+		s.Add(1, 2, 3)
+
+		if got := setTypeOf(s); got != st {
+			t.Errorf("setTypeOf: should be %d, got %d", st, got)
+		}
+
+		for _, r := range []Set[int]{s.Copy(), s.AddNew(4), s.RemoveReturning(4), s.DifferenceSlice(nil), s.SplitN(1)[0]} {
+			conv, ok := r.(*SetAdaptive[int])
+			if !ok || conv.threadSafe() != (st == ThreadSafe) {
+				t.Errorf("%v: result should be an adaptive set of the same type, got %T", s, r)
+			}
+		}
+	}
+}
+
+func TestSetAdaptive_Self(t *testing.T) {
+	s := NewAdaptive[int](ThreadSafe)
+	s.Add(1, 2, 3)
+
+	// none of these may deadlock
+	if !s.IsEqual(s) || !s.IsSubset(s) || !s.IsSuperset(s) || !s.ContainsAnySet(s) {
+		t.Error("a set should equal itself")
+	}
+
+	s.Merge(s)
+	if s.Size() != 3 {
+		t.Error("Merge: merging a set into itself should change nothing, got", s)
+	}
+
+	s.Separate(s)
+	if !s.IsEmpty() {
+		t.Error("Separate: separating a set from itself should empty it, got", s)
+	}
+}
+
+func TestSetAdaptive_OnSizeThreshold(t *testing.T) {
+	s := NewAdaptive[int](ThreadSafe)
+
+	var sizes []int
+	s.OnSizeThreshold(adaptiveLimit+1, func(size int) {
+		sizes = append(sizes, size)
+		s.Has(size) // must not deadlock
+	})
+
+	for i := 0; i < 2*adaptiveLimit; i++ {
+		s.Add(i)
+	}
+	if len(sizes) != 1 || sizes[0] != adaptiveLimit+1 {
+		t.Error("OnSizeThreshold: should fire once across promotion, got", sizes)
+	}
+}
+
+func TestSetAdaptive_Race(t *testing.T) {
+	s := NewAdaptive[int](ThreadSafe)
+
+	var wg sync.WaitGroup
+	for g := 0; g < 4; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for i := 0; i < 2*adaptiveLimit; i++ {
+				s.Add(g*100 + i)
+				s.Has(i)
+				s.List()
+			}
+		}(g)
+	}
+	wg.Wait()
+
+	if s.Size() != 8*adaptiveLimit {
+		t.Error("Add: concurrent adds should all be retained, got", s.Size())
+	}
+}
+
+func BenchmarkAdaptive(b *testing.B) {
+	impls := map[string]func() Set[int]{
+		"Adaptive": func() Set[int] { return NewAdaptive[int](NonThreadSafe) },
+		"Small":    NewSmall[int],
+		"Map":      func() Set[int] { return newNonTS[int]() },
+	}
+
+	for name, newSet := range impls {
+		// transition: building up a set from empty past the promotion
+		b.Run(fmt.Sprintf("Add/%s/%d", name, 2*adaptiveLimit), func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				s := newSet()
+				for j := 0; j < 2*adaptiveLimit; j++ {
+					s.Add(j)
+				}
+			}
+		})
+
+		// steady state: lookups well below and well above the promotion
+		for _, n := range []int{adaptiveLimit / 2, 8 * adaptiveLimit} {
+			b.Run(fmt.Sprintf("Has/%s/%d", name, n), func(b *testing.B) {
+				benchmarkSmallHas(b, newSet(), n)
+			})
+		}
+	}
+}
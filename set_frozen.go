@@ -0,0 +1,111 @@
+package set
+
+// SetFrozen defines a read-only set data structure, holding a point-in-time
+// copy of another set. As it never changes, it's safe for concurrent reads
+// without any locking, and can be published to other goroutines freely. All
+// methods which would modify it panic.
+type SetFrozen[T comparable] struct {
+	set[T]
+}
+
+// newFrozen creates a new frozen Set holding the items of m, which must not
+// be modified afterwards.
+func newFrozen[T comparable](m map[T]T) *SetFrozen[T] {
+	s := &SetFrozen[T]{}
+	s.m = m
+
+	// Ensure interface compliance
+	var _ Set[T] = s
+
+	return s
+}
+
+// NewEmpty returns a new empty non-thread-safe Set, as an empty frozen set
+// couldn't be filled.
+func (s *SetFrozen[T]) NewEmpty() Set[T] {
+	return newNonTS[T]()
+}
+
+// frozen panics, reporting that method was called on a frozen set.
+func frozen(method string) {
+	panic("set: " + method + " called on a frozen set")
+}
+
+// Add panics, as a frozen set can't be modified.
+func (s *SetFrozen[T]) Add(items ...T) { frozen("Add") }
+
+// AddAll panics, as a frozen set can't be modified.
+func (s *SetFrozen[T]) AddAll(items ...T) { frozen("AddAll") }
+
+// AddNew panics, as a frozen set can't be modified.
+func (s *SetFrozen[T]) AddNew(items ...T) Set[T] { frozen("AddNew"); return nil }
+
+// Intern panics, as a frozen set can't be modified.
+func (s *SetFrozen[T]) Intern(item T) T { frozen("Intern"); return item }
+
+// Toggle panics, as a frozen set can't be modified.
+func (s *SetFrozen[T]) Toggle(items ...T) { frozen("Toggle") }
+
+// ToggleReport panics, as a frozen set can't be modified.
+func (s *SetFrozen[T]) ToggleReport(items ...T) []bool { frozen("ToggleReport"); return nil }
+
+// Remove panics, as a frozen set can't be modified.
+func (s *SetFrozen[T]) Remove(items ...T) { frozen("Remove") }
+
+// RemoveReturning panics, as a frozen set can't be modified.
+func (s *SetFrozen[T]) RemoveReturning(items ...T) Set[T] { frozen("RemoveReturning"); return nil }
+
+// RemoveReport panics, as a frozen set can't be modified.
+func (s *SetFrozen[T]) RemoveReport(items ...T) (removed, notFound []T) {
+	frozen("RemoveReport")
+	return nil, nil
+}
+
+// Pop panics, as a frozen set can't be modified.
+func (s *SetFrozen[T]) Pop() (T, bool) {
+	frozen("Pop")
+	var zeroVal T
+	return zeroVal, false
+}
+
+// PopHint panics, as a frozen set can't be modified.
+func (s *SetFrozen[T]) PopHint(hint T) (T, bool) {
+	frozen("PopHint")
+	var zeroVal T
+	return zeroVal, false
+}
+
+// PopWhere panics, as a frozen set can't be modified.
+func (s *SetFrozen[T]) PopWhere(pred func(T) bool) Set[T] { frozen("PopWhere"); return nil }
+
+// OnSizeThreshold does nothing, as a frozen set never grows.
+func (s *SetFrozen[T]) OnSizeThreshold(n int, f func(size int)) {}
+
+// Grow panics, as a frozen set can't be modified.
+func (s *SetFrozen[T]) Grow(n int) { frozen("Grow") }
+
+// Clear panics, as a frozen set can't be modified.
+func (s *SetFrozen[T]) Clear() { frozen("Clear") }
+
+// Drain panics, as a frozen set can't be modified.
+func (s *SetFrozen[T]) Drain() []T { frozen("Drain"); return nil }
+
+// Compact panics, as a frozen set can't be modified.
+func (s *SetFrozen[T]) Compact() { frozen("Compact") }
+
+// NormalizeInPlace panics, as a frozen set can't be modified.
+func (s *SetFrozen[T]) NormalizeInPlace(f func(item T) T) { frozen("NormalizeInPlace") }
+
+// Merge panics, as a frozen set can't be modified.
+func (s *SetFrozen[T]) Merge(t Set[T]) { frozen("Merge") }
+
+// Separate panics, as a frozen set can't be modified.
+func (s *SetFrozen[T]) Separate(t Set[T]) { frozen("Separate") }
+
+// RetainSlice panics, as a frozen set can't be modified.
+func (s *SetFrozen[T]) RetainSlice(items []T) { frozen("RetainSlice") }
+
+// Snapshot returns s itself, as it never changes.
+func (s *SetFrozen[T]) Snapshot() Set[T] {
+	return s
+}
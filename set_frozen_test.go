@@ -0,0 +1,92 @@
+package set
+
+import (
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestSetFrozen_Snapshot(t *testing.T) {
+	for _, s := range []Set[string]{newTS[string](), newNonTS[string]()} {
+		s.Add("1", "2", "3")
+
+		r := s.Snapshot()
+		if _, ok := r.(*SetFrozen[string]); !ok {
+			t.Errorf("Snapshot: the snapshot should be frozen, got %T", r)
+		}
+
+		s.Add("4")
+		if r.Size() != 3 || !r.Has("1", "2", "3") {
+			t.Error("Snapshot: the snapshot should not reflect later modifications, got", r)
+		}
+
+		if r.Snapshot() != r {
+			t.Error("Snapshot: the snapshot of a frozen set should be itself")
+		}
+	}
+}
+
+func TestSetFrozen_Modify(t *testing.T) {
+	s := newNonTS[int]()
+	s.Add(1, 2, 3)
+	r := s.Snapshot()
+
+	mods := map[string]func(){
+		"Add":              func() { r.Add(4) },
+		"AddNew":           func() { r.AddNew(4) },
+		"Intern":           func() { r.Intern(4) },
+		"Remove":           func() { r.Remove(1) },
+		"RemoveReturning":  func() { r.RemoveReturning(1) },
+		"RemoveReport":     func() { r.RemoveReport(1) },
+		"Pop":              func() { r.Pop() },
+		"PopWhere":         func() { r.PopWhere(func(int) bool { return true }) },
+		"PopHint":          func() { r.PopHint(1) },
+		"Toggle":           func() { r.Toggle(1) },
+		"ToggleReport":     func() { r.ToggleReport(1) },
+		"AddAll":           func() { r.AddAll(1) },
+		"Grow":             func() { r.Grow(1) },
+		"Clear":            func() { r.Clear() },
+		"Drain":            func() { r.Drain() },
+		"Compact":          func() { r.Compact() },
+		"NormalizeInPlace": func() { r.NormalizeInPlace(func(n int) int { return n }) },
+		"Merge":            func() { r.Merge(s) },
+		"Separate":         func() { r.Separate(s) },
+		"RetainSlice":      func() { r.RetainSlice(nil) },
+	}
+
+	for method, mod := range mods {
+		func() {
+			defer func() {
+				if msg, _ := recover().(string); !strings.Contains(msg, method) {
+					t.Errorf("%s: should panic about a frozen set, got %q", method, msg)
+				}
+			}()
+			mod()
+		}()
+	}
+
+	if r.Size() != 3 {
+		t.Error("SetFrozen: the frozen set should not be modified, got", r)
+	}
+}
+
+func TestSetFrozen_RaceRead(t *testing.T) {
+	// "go test -race" should detect this if reads of a frozen set write.
+	s := newTS[int]()
+	s.Add(1, 2, 3)
+	r := s.Snapshot()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			r.Has(1)
+			r.List()
+			r.Hash()
+			r.IsEqual(s)
+			_ = r.String()
+		}()
+	}
+	wg.Wait()
+}
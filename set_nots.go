@@ -1,13 +1,44 @@
 package set
 
 import (
+	"encoding/binary"
 	"fmt"
+	"hash/fnv"
+	"io"
+	"iter"
+	"math"
+	"math/rand"
+	"reflect"
+	"sort"
 	"strings"
+	"sync"
+	"sync/atomic"
+	"unsafe"
 )
 
 // Provides a common set baseline for both threadsafe and non-ts Sets.
 type set[T comparable] struct {
-	m map[T]struct{} // struct{} doesn't take up space
+	m    map[T]T // each item maps to itself, so the stored one can be returned
+	hint int     // capacity last reserved for m, see Cap
+
+	// cached result of Hash, reset by changed. These are atomic as Hash may
+	// fill the cache from concurrent readers of a thread-safe set.
+	hashSum   atomic.Uint64
+	hashValid atomic.Bool
+
+	thresholds []sizeThreshold // registered by OnSizeThreshold
+}
+
+// sizeThreshold is a callback registered by OnSizeThreshold.
+type sizeThreshold struct {
+	n int
+	f func(size int)
+}
+
+// changed must be called after every mutation of s.m, to invalidate what's
+// cached about its contents.
+func (s *set[T]) changed() {
+	s.hashValid.Store(false)
 }
 
 // SetNonTS defines a non-thread safe set data structure.
@@ -18,7 +49,7 @@ type SetNonTS[T comparable] struct {
 // NewNonTS creates and initializes a new non-threadsafe Set.
 func newNonTS[T comparable]() *SetNonTS[T] {
 	s := &SetNonTS[T]{}
-	s.m = make(map[T]struct{})
+	s.m = make(map[T]T)
 
 	// Ensure interface compliance
 	var _ Set[T] = s
@@ -26,6 +57,57 @@ func newNonTS[T comparable]() *SetNonTS[T] {
 	return s
 }
 
+// NewEmpty returns a new empty non-thread-safe Set.
+func (s *set[T]) NewEmpty() Set[T] {
+	return newNonTS[T]()
+}
+
+// Add includes the specified items (one or more) to the set. The underlying
+// Set s is modified. If passed nothing it silently returns.
+func (s *SetNonTS[T]) Add(items ...T) {
+	s.adding(func() { s.set.Add(items...) })
+}
+
+// AddAll includes the specified items to the set, like Add, but first grows
+// the set to hold all of them. See set.AddAll for details.
+func (s *SetNonTS[T]) AddAll(items ...T) {
+	s.adding(func() { s.set.AddAll(items...) })
+}
+
+// AddNew includes the specified items to the set, like Add, and returns a new
+// set of those which weren't already present. An item passed more than once
+// is new only the first time.
+func (s *SetNonTS[T]) AddNew(items ...T) (added Set[T]) {
+	s.adding(func() { added = s.set.AddNew(items...) })
+	return added
+}
+
+// Intern adds item to the set if it isn't present yet, and returns the stored
+// item of the set equal to it. See set.Intern for details.
+func (s *SetNonTS[T]) Intern(item T) (stored T) {
+	s.adding(func() { stored = s.set.Intern(item) })
+	return stored
+}
+
+// Merge is like Union, however it modifies the current set it's applied on
+// with the given t set.
+func (s *SetNonTS[T]) Merge(t Set[T]) {
+	s.adding(func() { s.set.Merge(t) })
+}
+
+// Toggle removes each of the specified items which is in the set, and adds
+// each one which isn't. See set.Toggle for details.
+func (s *SetNonTS[T]) Toggle(items ...T) {
+	s.adding(func() { s.set.Toggle(items...) })
+}
+
+// ToggleReport is like Toggle, and returns whether each item is in the set
+// after toggling it. See set.ToggleReport for details.
+func (s *SetNonTS[T]) ToggleReport(items ...T) (has []bool) {
+	s.adding(func() { has = s.set.ToggleReport(items...) })
+	return has
+}
+
 // Add includes the specified items (one or more) to the set. The underlying
 // Set s is modified. If passed nothing it silently returns.
 func (s *set[T]) Add(items ...T) {
@@ -34,8 +116,70 @@ func (s *set[T]) Add(items ...T) {
 	}
 
 	for _, item := range items {
-		s.m[item] = keyExists
+		s.m[item] = item
+	}
+	s.changed()
+}
+
+// AddAll includes the specified items to the set, like Add, but first grows
+// the set to hold all of them, so adding many items doesn't rehash the map
+// repeatedly. Items already in the set or passed more than once are counted
+// too, which may over-reserve.
+func (s *set[T]) AddAll(items ...T) {
+	s.Grow(len(items))
+	s.Add(items...)
+}
+
+// AddNew includes the specified items to the set, like Add, and returns a new
+// set of those which weren't already present. An item passed more than once
+// is new only the first time.
+func (s *set[T]) AddNew(items ...T) Set[T] {
+	added := newNonTS[T]()
+	s.addNewInto(added, items)
+	return added
+}
+
+// addNewInto includes items to s, adding those which weren't present to added.
+func (s *set[T]) addNewInto(added Set[T], items []T) {
+	for _, item := range items {
+		if _, has := s.m[item]; !has {
+			s.m[item] = item
+			added.Add(item)
+		}
+	}
+	s.changed()
+}
+
+// Toggle removes each of the specified items which is in the set, and adds
+// each one which isn't, like a symmetric difference of the set and items. The
+// items are toggled in turn, so an item passed twice is toggled back.
+func (s *set[T]) Toggle(items ...T) {
+	s.toggle(items, nil)
+}
+
+// ToggleReport is like Toggle, and returns whether each item is in the set
+// after toggling it, in the same order as the items.
+func (s *set[T]) ToggleReport(items ...T) []bool {
+	has := make([]bool, len(items))
+	s.toggle(items, has)
+	return has
+}
+
+// toggle toggles each of items in s, recording whether it's in s afterwards at
+// the same index of has, unless has is nil.
+func (s *set[T]) toggle(items []T, has []bool) {
+	for i, item := range items {
+		_, present := s.m[item]
+		if present {
+			delete(s.m, item)
+		} else {
+			s.m[item] = item
+		}
+		if has != nil {
+			has[i] = !present
+		}
 	}
+	s.changed()
 }
 
 // Remove deletes the specified items from the set.  The underlying Set s is
@@ -48,6 +192,44 @@ func (s *set[T]) Remove(items ...T) {
 	for _, item := range items {
 		delete(s.m, item)
 	}
+	s.changed()
+}
+
+// RemoveReturning deletes the specified items from the set, like Remove, and
+// returns a new set of those which were actually present and removed.
+func (s *set[T]) RemoveReturning(items ...T) Set[T] {
+	removed := newNonTS[T]()
+	s.removeInto(removed, items)
+	return removed
+}
+
+// removeInto deletes the specified items from s, adding those which were
+// present to removed.
+func (s *set[T]) removeInto(removed Set[T], items []T) {
+	for _, item := range items {
+		if _, has := s.m[item]; has {
+			delete(s.m, item)
+			removed.Add(item)
+		}
+	}
+	s.changed()
+}
+
+// RemoveReport deletes the specified items from the set, like Remove, and
+// returns those which were removed and those which weren't present, each in
+// the order of items. An item passed more than once is removed only the first
+// time, and not found afterwards.
+func (s *set[T]) RemoveReport(items ...T) (removed, notFound []T) {
+	for _, item := range items {
+		if _, has := s.m[item]; has {
+			delete(s.m, item)
+			removed = append(removed, item)
+		} else {
+			notFound = append(notFound, item)
+		}
+	}
+	s.changed()
+	return removed, notFound
 }
 
 // Pop  deletes and return an item from the set. The underlying Set s is
@@ -55,12 +237,45 @@ func (s *set[T]) Remove(items ...T) {
 func (s *set[T]) Pop() (T, bool) {
 	for item := range s.m {
 		delete(s.m, item)
+		s.changed()
 		return item, true
 	}
 	var zeroVal T
 	return zeroVal, false
 }
 
+// PopHint deletes and returns hint if it's in the set, and otherwise an
+// arbitrary item like Pop. Only if the set is empty, the zero value and false
+// are returned.
+func (s *set[T]) PopHint(hint T) (T, bool) {
+	if _, ok := s.m[hint]; ok {
+		delete(s.m, hint)
+		s.changed()
+		return hint, true
+	}
+	return s.Pop()
+}
+
+// PopWhere deletes every item of the set for which pred returns true, and
+// returns them as a new set.
+func (s *set[T]) PopWhere(pred func(T) bool) Set[T] {
+	popped := newNonTS[T]()
+	s.popWhereInto(popped, pred)
+	return popped
+}
+
+// popWhereInto deletes every item of s for which pred returns true, adding
+// them to popped.
+func (s *set[T]) popWhereInto(popped Set[T], pred func(T) bool) {
+	for item := range s.m {
+		if pred(item) {
+			delete(s.m, item)
+			popped.Add(item)
+		}
+	}
+	s.changed()
+}
+
 // Has looks for the existence of items passed. It returns false if nothing is
 // passed. For multiple items it returns true only if all of  the items exist.
 func (s *set[T]) Has(items ...T) bool {
@@ -78,14 +293,190 @@ func (s *set[T]) Has(items ...T) bool {
 	return has
 }
 
+// HasAll is an explicit alias of Has. It returns true only if all of the
+// passed items exist, and false if nothing is passed.
+func (s *set[T]) HasAll(items ...T) bool {
+	return s.Has(items...)
+}
+
+// HasAny looks for the existence of items passed. It returns false if nothing
+// is passed. For multiple items it returns true if at least one of the items
+// exists.
+func (s *set[T]) HasAny(items ...T) bool {
+	for _, item := range items {
+		if _, has := s.m[item]; has {
+			return true
+		}
+	}
+	return false
+}
+
+// HasEach looks for the existence of each item passed. It returns a slice
+// reporting the membership of each item, in the same order as the items.
+func (s *set[T]) HasEach(items ...T) []bool {
+	has := make([]bool, len(items))
+	for i, item := range items {
+		_, has[i] = s.m[item]
+	}
+	return has
+}
+
+// Get looks up item, returning the stored item of the set equal to it and
+// true, or the zero value and false if there's none. It's meant for sets used
+// as interning pools, to reuse the stored item instead of an equal one, e.g.
+// a string sharing its bytes or a canonical pointer. Add replaces the stored
+// item with the one passed, while Intern keeps it.
+func (s *set[T]) Get(item T) (T, bool) {
+	stored, ok := s.m[item]
+	return stored, ok
+}
+
+// Intern adds item to the set if it isn't present yet, and returns the stored
+// item of the set equal to it, which is item itself only if it was added. It's
+// the core operation of an interning pool; unlike Add, it never replaces the
+// stored item.
+func (s *set[T]) Intern(item T) T {
+	if stored, ok := s.m[item]; ok {
+		return stored
+	}
+	s.m[item] = item
+	s.changed()
+	return item
+}
+
+// OnSizeThreshold registers f to be called whenever adding items grows the
+// set from less than n items to n or more. It only fires on such growth
+// crossings: not when the set already holds n items at registration, nor
+// when it shrinks. f is passed the size after the addition. Thresholds are
+// checked by Add, AddAll, AddNew and Merge, and by the functions built on them.
+func (s *set[T]) OnSizeThreshold(n int, f func(size int)) {
+	s.thresholds = append(s.thresholds, sizeThreshold{n, f})
+}
+
+// adding calls f, which adds items to s, and then the callbacks registered by
+// OnSizeThreshold whose threshold f crossed.
+func (s *set[T]) adding(f func()) {
+	before := len(s.m)
+	f()
+	notify(s.crossed(before), len(s.m))
+}
+
+// crossed returns the callbacks registered by OnSizeThreshold whose threshold
+// was crossed by growing from before to the current size.
+func (s *set[T]) crossed(before int) []func(size int) {
+	return thresholdsCrossed(s.thresholds, before, len(s.m))
+}
+
+// thresholdsCrossed returns the callbacks of ths whose threshold was crossed
+// by growing from before to after items.
+func thresholdsCrossed(ths []sizeThreshold, before, after int) []func(size int) {
+	var fs []func(size int)
+	for _, th := range ths {
+		if before < th.n && after >= th.n {
+			fs = append(fs, th.f)
+		}
+	}
+	return fs
+}
+
+// notify calls each of fs with size.
+func notify(fs []func(size int), size int) {
+	for _, f := range fs {
+		f(size)
+	}
+}
+
 // Size returns the number of items in a set.
 func (s *set[T]) Size() int {
 	return len(s.m)
 }
 
+// EstimatedBytes returns a rough estimate of the heap size of the set. See
+// estimateBytes for the formula used.
+func (s *set[T]) EstimatedBytes() int {
+	return estimateBytes[T](len(s.m))
+}
+
+// estimateBytes approximates the heap size of a map[T]T holding n items as:
+//
+//	48 + n * (2 * sizeof(T) + 1) * 5 / 4
+//
+// which is the map header, plus each key, the same item as its value, and its
+// byte of hash metadata, inflated by the map's load factor. Memory referenced by the items, like the
+// bytes of a string, isn't included.
+func estimateBytes[T comparable](n int) int {
+	var zero T
+	return 48 + n*(2*int(unsafe.Sizeof(zero))+1)*5/4
+}
+
 // Clear removes all items from the set.
 func (s *set[T]) Clear() {
-	s.m = make(map[T]struct{})
+	s.m = make(map[T]T)
+	s.hint = 0
+	s.changed()
+}
+
+// Drain removes all items from the set, like Clear, and returns them. The
+// order of the items is unspecified, as with List. If the set is empty, an
+// empty slice is returned.
+func (s *set[T]) Drain() []T {
+	items := s.List()
+	s.Clear()
+	return items
+}
+
+// Compact rebuilds the backing map at the current size. Go maps don't release
+// memory when items are removed, so this reclaims it for a set which has
+// shrunk considerably.
+func (s *set[T]) Compact() {
+	m := make(map[T]T, len(s.m))
+	for item := range s.m {
+		m[item] = item
+	}
+	s.m = m
+	s.hint = len(m)
+}
+
+// NormalizeInPlace replaces every item of the set with f(item), e.g. to
+// lowercase all strings, collapsing items which f maps to the same one. As map
+// keys can't be changed in place, this builds a new backing map and swaps it
+// in, so the set keeps its identity.
+func (s *set[T]) NormalizeInPlace(f func(item T) T) {
+	m := make(map[T]T, len(s.m))
+	for item := range s.m {
+		item = f(item)
+		m[item] = item
+	}
+	s.m = m
+	s.hint = len(m)
+	s.changed()
+}
+
+// Cap returns a best-effort estimate of the number of items the set can hold
+// without growing its backing map. Go doesn't expose the capacity of a map, so
+// this is the capacity last reserved via Grow or Compact, or the current size
+// if that is larger.
+func (s *set[T]) Cap() int {
+	if s.hint > len(s.m) {
+		return s.hint
+	}
+	return len(s.m)
+}
+
+// Grow ensures the set can hold n more items without growing its backing
+// map. If it can't already, the backing map is rebuilt with room for them.
+func (s *set[T]) Grow(n int) {
+	want := len(s.m) + n
+	if n <= 0 || want <= s.Cap() {
+		return
+	}
+
+	m := make(map[T]T, want)
+	for item := range s.m {
+		m[item] = item
+	}
+	s.m = m
+	s.hint = want
 }
 
 // IsEmpty reports whether the Set is empty.
@@ -96,16 +487,29 @@ func (s *set[T]) IsEmpty() bool {
 // IsEqual test whether s and t are the same in size and have the same items.
 func (s *set[T]) IsEqual(t Set[T]) bool {
 	// Force locking only if given set is threadsafe.
-	if conv, ok := t.(RWLockable); ok {
-		conv.RLock()
-		defer conv.RUnlock()
-	}
+	t, unlock := readLocked(t)
+	defer unlock()
+
+	return s.isEqual(t)
+}
 
+// isEqual is IsEqual without locking t, which must be locked already.
+func (s *set[T]) isEqual(t Set[T]) bool {
 	// return false if they are no the same size
 	if sameSize := len(s.m) == t.Size(); !sameSize {
 		return false
 	}
 
+	// or if both have a cached hash which differs. Equal hashes may collide,
+	// so the items are compared regardless.
+	if conv, ok := t.(interface{ cachedHash() (uint64, bool) }); ok {
+		sum, ok := s.cachedHash()
+		tSum, tOk := conv.cachedHash()
+		if ok && tOk && sum != tSum {
+			return false
+		}
+	}
+
 	equal := true
 	t.Each(func(item T) bool {
 		_, equal = s.m[item]
@@ -116,7 +520,21 @@ func (s *set[T]) IsEqual(t Set[T]) bool {
 }
 
 // IsSubset tests whether t is a subset of s.
-func (s *set[T]) IsSubset(t Set[T]) (subset bool) {
+func (s *set[T]) IsSubset(t Set[T]) bool {
+	// Force locking only if given set is threadsafe.
+	t, unlock := readLocked(t)
+	defer unlock()
+
+	return s.isSubset(t)
+}
+
+// isSubset is IsSubset without locking t, which must be locked already.
+func (s *set[T]) isSubset(t Set[T]) (subset bool) {
+	// t can't be a subset if it has more items, so skip the scan
+	if t.Size() > len(s.m) {
+		return false
+	}
+
 	subset = true
 
 	t.Each(func(item T) bool {
@@ -129,13 +547,57 @@ func (s *set[T]) IsSubset(t Set[T]) (subset bool) {
 
 // IsSuperset tests whether t is a superset of s.
 func (s *set[T]) IsSuperset(t Set[T]) bool {
-	return t.IsSubset(s)
+	return orEmpty(t).IsSubset(s)
+}
+
+// ContainsSet tests whether every item of t is in s. It's the same as
+// s.IsSubset(t), which reads less naturally.
+func (s *set[T]) ContainsSet(t Set[T]) bool {
+	return s.IsSubset(t)
+}
+
+// ContainsAnySet tests whether any item of t is in s. It iterates the smaller
+// of both sets.
+func (s *set[T]) ContainsAnySet(t Set[T]) bool {
+	// Force locking only if given set is threadsafe.
+	t, unlock := readLocked(t)
+	defer unlock()
+
+	return s.containsAnySet(t)
+}
+
+// containsAnySet is ContainsAnySet without locking t, which must be locked
+// already.
+func (s *set[T]) containsAnySet(t Set[T]) (found bool) {
+	if len(s.m) <= t.Size() {
+		for item := range s.m {
+			if t.Has(item) {
+				return true
+			}
+		}
+		return false
+	}
+
+	t.Each(func(item T) bool {
+		_, found = s.m[item]
+		return !found
+	})
+	return found
 }
 
 // Each traverses the items in the Set, calling the provided function for each
 // set member. Traversal will continue until all items in the Set have been
 // visited, or if the closure returns false.
 func (s *set[T]) Each(f func(item T) bool) {
+	if list, ok := s.seededList(); ok {
+		for _, item := range list {
+			if !f(item) {
+				break
+			}
+		}
+		return
+	}
+
 	for item := range s.m {
 		if !f(item) {
 			break
@@ -143,6 +605,117 @@ func (s *set[T]) Each(f func(item T) bool) {
 	}
 }
 
+// EachSnapshot is like Each, but traverses a snapshot of the items taken at
+// call time, so the closure may modify the set.
+func (s *set[T]) EachSnapshot(f func(item T) bool) {
+	eachOf(s.List(), f)
+}
+
+// eachOf calls f for each of items until it returns false.
+func eachOf[T comparable](items []T, f func(item T) bool) {
+	for _, item := range items {
+		if !f(item) {
+			break
+		}
+	}
+}
+
+// EachErr traverses the items in the Set, calling the provided function for
+// each set member. Traversal stops at the first error returned by the closure,
+// which is then returned. A nil error means all items have been visited.
+func (s *set[T]) EachErr(f func(item T) error) (err error) {
+	s.Each(func(item T) bool {
+		err = f(item)
+		return err == nil
+	})
+	return err
+}
+
+// EachIndexed is like Each, but also passes a running index, starting at
+// zero, to the closure. The index follows the iteration order, which is
+// unspecified.
+func (s *set[T]) EachIndexed(f func(i int, item T) bool) {
+	i := 0
+	s.Each(func(item T) bool {
+		ok := f(i, item)
+		i++
+		return ok
+	})
+}
+
+// EachBatch traverses the items in the Set like Each, but passes them to the
+// closure in batches of up to batchSize items, the last one possibly smaller.
+// Each batch is a new slice which the closure may keep. Traversal stops when
+// the closure returns false. A batchSize less than one is treated as one.
+func (s *set[T]) EachBatch(batchSize int, f func(batch []T) bool) {
+	eachBatch(s.Each, batchSize, f)
+}
+
+// eachBatch collects the items traversed by each into batches of up to
+// batchSize items, and passes them to f until it returns false.
+func eachBatch[T comparable](each func(func(T) bool), batchSize int, f func(batch []T) bool) {
+	batchSize = max(batchSize, 1)
+
+	batch := make([]T, 0, batchSize)
+	ok := true
+	each(func(item T) bool {
+		batch = append(batch, item)
+		if len(batch) < batchSize {
+			return true
+		}
+		ok = f(batch)
+		batch = make([]T, 0, batchSize)
+		return ok
+	})
+	if ok && len(batch) > 0 {
+		f(batch)
+	}
+}
+
+// EachParallel calls f for each item of a snapshot of the set, taken at call
+// time, from workers goroutines at once, and returns once all items have been
+// processed. This pays off when f is CPU-bound and dominates the cost of
+// traversal. f must be safe for concurrent use, and the order in which items
+// are processed is unspecified. A workers count less than one is treated as
+// one.
+func (s *set[T]) EachParallel(workers int, f func(item T)) {
+	eachParallel(s.List(), workers, f)
+}
+
+// eachParallel calls f for each of items from workers goroutines, each taking
+// the next unprocessed item until none are left.
+func eachParallel[T comparable](items []T, workers int, f func(item T)) {
+	workers = min(max(workers, 1), len(items))
+
+	var next atomic.Int64
+	var wg sync.WaitGroup
+	wg.Add(workers)
+	for w := 0; w < workers; w++ {
+		go func() {
+			defer wg.Done()
+			for i := next.Add(1) - 1; i < int64(len(items)); i = next.Add(1) - 1 {
+				f(items[i])
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+// Iterator returns an iterator over a snapshot of the items of the set, taken
+// at call time.
+func (s *set[T]) Iterator() *Iterator[T] {
+	return newIterator(s.List())
+}
+
+// Keys returns a sequence of the items of the set. Unlike List, it's a live
+// view over the set rather than a copy, so it doesn't allocate; it must not be
+// used while the set is modified.
+func (s *set[T]) Keys() iter.Seq[T] {
+	return func(yield func(T) bool) {
+		s.Each(yield)
+	}
+}
+
 // Copy returns a new Set with a copy of s.
 func (s *set[T]) Copy() Set[T] {
 	u := newNonTS[T]()
@@ -152,6 +725,79 @@ func (s *set[T]) Copy() Set[T] {
 	return u
 }
 
+// CopyWithCap returns a new Set with a copy of s, like Copy, with room for
+// extra more items reserved, so adding them to the copy doesn't grow it.
+// extra is a hint, not a limit: the copy grows past it as needed.
+func (s *set[T]) CopyWithCap(extra int) Set[T] {
+	u := newNonTS[T]()
+	s.copyWithCap(&u.set, extra)
+	return u
+}
+
+// copyWithCap fills the empty u with the items of s, reserving room for extra
+// more.
+func (s *set[T]) copyWithCap(u *set[T], extra int) {
+	want := len(s.m) + max(extra, 0)
+	u.m = make(map[T]T, want)
+	u.hint = want
+	for item := range s.m {
+		u.m[item] = item
+	}
+	u.changed()
+}
+
+// Snapshot returns a read-only copy of s, which panics if modified. Unlike a
+// Copy, it's safe for concurrent reads without locking.
+func (s *set[T]) Snapshot() Set[T] {
+	m := make(map[T]T, len(s.m))
+	for item := range s.m {
+		m[item] = item
+	}
+	return newFrozen(m)
+}
+
+// SplitN partitions the items of s into n new sets, e.g. to process them in
+// parallel. Each item ends up in exactly one of the sets, and their sizes
+// differ by at most one. If n is less than one, nil is returned.
+func (s *set[T]) SplitN(n int) []Set[T] {
+	return splitN(s.List(), n, func() Set[T] { return newNonTS[T]() })
+}
+
+// splitN distributes items round-robin over n sets created by newSet.
+func splitN[T comparable](items []T, n int, newSet func() Set[T]) []Set[T] {
+	if n < 1 {
+		return nil
+	}
+
+	sets := make([]Set[T], n)
+	for i := range sets {
+		sets[i] = newSet()
+		sets[i].Grow(len(items)/n + 1)
+	}
+	for i, item := range items {
+		sets[i%n].Add(item)
+	}
+	return sets
+}
+
+// AsThreadSafe returns a new thread-safe Set with a copy of s.
+func (s *set[T]) AsThreadSafe() Set[T] {
+	u := newTS[T]()
+	for item := range s.m {
+		u.m[item] = item
+	}
+	return u
+}
+
+// AsNonThreadSafe returns a new non-thread-safe Set with a copy of s.
+func (s *set[T]) AsNonThreadSafe() Set[T] {
+	u := newNonTS[T]()
+	for item := range s.m {
+		u.m[item] = item
+	}
+	return u
+}
+
 // String returns a string representation of s
 func (s *set[T]) String() string {
 	t := make([]string, 0, len(s.List()))
@@ -162,9 +808,151 @@ func (s *set[T]) String() string {
 	return fmt.Sprintf("[%s]", strings.Join(t, ", "))
 }
 
+// StringFunc returns a string representation of s, formatting each item with
+// format and separating them with sep. Unlike String, the result isn't
+// enclosed in square brackets. If format is nil, items are formatted with %v.
+func (s *set[T]) StringFunc(sep string, format func(T) string) string {
+	if format == nil {
+		format = func(item T) string { return fmt.Sprintf("%v", item) }
+	}
+
+	t := make([]string, 0, len(s.m))
+	for _, item := range s.List() {
+		t = append(t, format(item))
+	}
+
+	return strings.Join(t, sep)
+}
+
+// WriteTo writes the items of s to w, one per line as formatted by
+// fmt.Fprintln, without building the whole text in memory. It implements
+// io.WriterTo, returning the number of bytes written and the first error, at
+// which it stops. Each item is a separate write, so wrap w in a bufio.Writer
+// if that's costly.
+func (s *set[T]) WriteTo(w io.Writer) (n int64, err error) {
+	s.Each(func(item T) bool {
+		n, err = writeLine(w, n, item)
+		return err == nil
+	})
+	return n, err
+}
+
+// writeItems writes items to w like WriteTo.
+func writeItems[T comparable](w io.Writer, items []T) (n int64, err error) {
+	for _, item := range items {
+		if n, err = writeLine(w, n, item); err != nil {
+			break
+		}
+	}
+	return n, err
+}
+
+// writeLine writes item to w as a line, returning n plus the bytes written.
+func writeLine[T comparable](w io.Writer, n int64, item T) (int64, error) {
+	written, err := fmt.Fprintln(w, item)
+	return n + int64(written), err
+}
+
+// Hash returns a hash of the items of s, which is independent of the order
+// they're traversed in. Equal sets have the same hash, but unequal sets may
+// collide. Each item is hashed as described at hashItem, consistently with
+// ==; use HashFunc to hash items differently.
+//
+// The hash is cached until s is modified, and used by IsEqual to tell sets
+// apart quickly.
+func (s *set[T]) Hash() uint64 {
+	if sum, ok := s.cachedHash(); ok {
+		return sum
+	}
+
+	var sum uint64
+	for item := range s.m {
+		sum += hashItem(item)
+	}
+	s.hashSum.Store(sum)
+	s.hashValid.Store(true)
+	return sum
+}
+
+// cachedHash returns the hash cached by Hash, if s hasn't changed since.
+func (s *set[T]) cachedHash() (uint64, bool) {
+	if !s.hashValid.Load() {
+		return 0, false
+	}
+	return s.hashSum.Load(), true
+}
+
+// hashItem returns the FNV-1a hash of the bytes appendHashed encodes item to,
+// so items equal by == have the same hash, unlike with their %v
+// representation: 0.0 and -0.0 are equal but format differently, and a
+// pointer formats as what it points to, which may change. The hashes of all
+// items are summed, as addition is commutative.
+func hashItem[T comparable](item T) uint64 {
+	h := fnv.New64a()
+	h.Write(appendHashed(nil, reflect.ValueOf(&item).Elem()))
+	return h.Sum64()
+}
+
+// appendHashed appends an encoding of v to b which is the same for values
+// equal by ==. Pointers and channels are encoded by their address, and an
+// interface by its dynamic type and value. Floats are normalized so that 0.0
+// and -0.0 match; NaN never equals anything, so its encoding doesn't matter.
+func appendHashed(b []byte, v reflect.Value) []byte {
+	switch v.Kind() {
+	case reflect.Bool:
+		if v.Bool() {
+			return append(b, 1)
+		}
+		return append(b, 0)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return binary.LittleEndian.AppendUint64(b, uint64(v.Int()))
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return binary.LittleEndian.AppendUint64(b, v.Uint())
+	case reflect.Float32, reflect.Float64:
+		return appendFloat(b, v.Float())
+	case reflect.Complex64, reflect.Complex128:
+		c := v.Complex()
+		return appendFloat(appendFloat(b, real(c)), imag(c))
+	case reflect.String:
+		b = binary.LittleEndian.AppendUint64(b, uint64(v.Len())) // so "a","bc" differs from "ab","c"
+		return append(b, v.String()...)
+	case reflect.Pointer, reflect.Chan, reflect.UnsafePointer:
+		return binary.LittleEndian.AppendUint64(b, uint64(v.Pointer()))
+	case reflect.Interface:
+		if v.IsNil() {
+			return append(b, 0)
+		}
+		b = append(append(b, 1), v.Elem().Type().String()...)
+		return appendHashed(b, v.Elem())
+	case reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			b = appendHashed(b, v.Index(i))
+		}
+	case reflect.Struct:
+		for i := 0; i < v.NumField(); i++ {
+			if v.Type().Field(i).Name != "_" { // blank fields aren't compared
+				b = appendHashed(b, v.Field(i))
+			}
+		}
+	}
+	return b
+}
+
+// appendFloat appends the bits of f to b, with -0.0 turned into 0.0.
+func appendFloat(b []byte, f float64) []byte {
+	if f == 0 {
+		f = 0
+	}
+	return binary.LittleEndian.AppendUint64(b, math.Float64bits(f))
+}
+
 // List returns a slice of all items. There is also StringSlice() and
 // IntSlice() methods for returning slices of type string or int.
 func (s *set[T]) List() []T {
+	if list, ok := s.seededList(); ok {
+		return list
+	}
+
 	list := make([]T, 0, len(s.m))
 
 	for item := range s.m {
@@ -174,17 +962,161 @@ func (s *set[T]) List() []T {
 	return list
 }
 
+// Stream returns a channel over which all items of the set are sent, after
+// which the channel is closed. The items are a snapshot taken at call time;
+// later mutations of the set are not reflected.
+func (s *set[T]) Stream() <-chan T {
+	return stream(s.List())
+}
+
+// stream returns a closed channel buffered with all of the given items, so
+// that a consumer that stops early doesn't leak a sending goroutine.
+func stream[T comparable](items []T) <-chan T {
+	ch := make(chan T, len(items))
+	for _, item := range items {
+		ch <- item
+	}
+	close(ch)
+	return ch
+}
+
+// Tee returns n channels, over each of which all items of the set are sent,
+// after which the channels are closed. The items are a single snapshot taken
+// at call time, shared by all channels. Each channel is fed by its own
+// goroutine, so consumers don't hold up each other, but every channel must be
+// drained for its goroutine to finish. If n is less than one, nil is returned.
+func (s *set[T]) Tee(n int) []<-chan T {
+	return tee(s.List(), n)
+}
+
+// tee fans out items to n channels, each fed by its own goroutine.
+func tee[T comparable](items []T, n int) []<-chan T {
+	if n < 1 {
+		return nil
+	}
+
+	chans := make([]<-chan T, n)
+	for i := range chans {
+		ch := make(chan T)
+		go func() {
+			for _, item := range items {
+				ch <- item
+			}
+			close(ch)
+		}()
+		chans[i] = ch
+	}
+	return chans
+}
+
 // Merge is like Union, however it modifies the current set it's applied on
 // with the given t set.
 func (s *set[T]) Merge(t Set[T]) {
-	t.Each(func(item T) bool {
-		s.m[item] = keyExists
+	orEmpty(t).Each(func(item T) bool {
+		s.m[item] = item
 		return true
 	})
+	s.changed()
 }
 
-// it's not the opposite of Merge.
 // Separate removes the set items containing in t from set s. Please aware that
+// it's not the opposite of Merge.
 func (s *set[T]) Separate(t Set[T]) {
-	s.Remove(t.List()...)
+	s.Remove(orEmpty(t).List()...)
+}
+
+// RetainSlice removes the items of s which don't appear in items, leaving the
+// intersection of s and items.
+func (s *set[T]) RetainSlice(items []T) {
+	keep := make(map[T]struct{}, len(items))
+	for _, item := range items {
+		keep[item] = keyExists
+	}
+
+	for item := range s.m {
+		if _, ok := keep[item]; !ok {
+			delete(s.m, item)
+		}
+	}
+	s.changed()
+}
+
+// DifferenceSlice returns a new set which contains the items of s which don't
+// appear in items.
+func (s *set[T]) DifferenceSlice(items []T) Set[T] {
+	u := newNonTS[T]()
+	s.differenceSliceInto(u, items)
+	return u
+}
+
+// IsSubsetSlice tests whether every item of s appears in items, without
+// building a set of items. Unlike IsSubset, s is the subset here. Duplicates
+// in items don't affect the result.
+func (s *set[T]) IsSubsetSlice(items []T) bool {
+	found := make(map[T]struct{}, min(len(s.m), len(items)))
+	for _, item := range items {
+		if len(found) == len(s.m) {
+			break
+		}
+		if _, ok := s.m[item]; ok {
+			found[item] = keyExists
+		}
+	}
+	return len(found) == len(s.m)
+}
+
+// IsSupersetSlice tests whether every one of items is in s, without building a
+// set of items. It's true for an empty slice, unlike Has.
+func (s *set[T]) IsSupersetSlice(items []T) bool {
+	for _, item := range items {
+		if _, ok := s.m[item]; !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// differenceSliceInto adds the items of s which don't appear in items to u.
+func (s *set[T]) differenceSliceInto(u Set[T], items []T) {
+	drop := make(map[T]struct{}, len(items))
+	for _, item := range items {
+		drop[item] = keyExists
+	}
+
+	for item := range s.m {
+		if _, ok := drop[item]; !ok {
+			u.Add(item)
+		}
+	}
+}
+
+// seededList returns the items of s in the deterministic order set up by
+// SetIterationSeed, or false if it isn't enabled. The items are sorted by
+// their %#v representation and then shuffled by the seed. Items which format
+// the same way are left in unspecified order relative to each other.
+func (s *set[T]) seededList() ([]T, bool) {
+	seed := iterationSeed.Load()
+	if seed == nil {
+		return nil, false
+	}
+
+	type keyed struct {
+		key  string
+		item T
+	}
+	sorted := make([]keyed, 0, len(s.m))
+	for item := range s.m {
+		sorted = append(sorted, keyed{fmt.Sprintf("%#v", item), item})
+	}
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].key < sorted[j].key })
+
+	list := make([]T, len(sorted))
+	for i, k := range sorted {
+		list[i] = k.item
+	}
+
+	rand.New(rand.NewSource(*seed)).Shuffle(len(list), func(i, j int) {
+		list[i], list[j] = list[j], list[i]
+	})
+	return list, true
 }
@@ -0,0 +1,646 @@
+package set
+
+import (
+	"cmp"
+	"fmt"
+	"io"
+	"iter"
+	"slices"
+	"strings"
+	"unsafe"
+)
+
+// SetSmall defines a non-thread-safe set data structure backed by a sorted
+// slice rather than a map, for sets which are almost always tiny. Membership
+// is tested by binary search. It takes far less memory than a map set, and
+// lookups are on par with one up to about 64 items but fall behind beyond
+// that, while adding or removing an item shifts all items after it. So it
+// pays off for sets of up to a few dozen items, and a map set should be used
+// for sets which may grow larger; see BenchmarkSmall.
+//
+// Items are ordered by cmp.Compare, so it's the order of traversal too,
+// regardless of SetIterationSeed. Unlike a map, cmp.Compare considers NaNs
+// equal, so the set holds at most one NaN.
+type SetSmall[T cmp.Ordered] struct {
+	items      []T             // sorted, without duplicates
+	thresholds []sizeThreshold // registered by OnSizeThreshold
+}
+
+// NewSmall creates and initializes a new Set backed by a sorted slice, for
+// sets which are almost always tiny. See SetSmall for when it pays off.
+func NewSmall[T cmp.Ordered]() Set[T] {
+	return newSmall[T]()
+}
+
+func newSmall[T cmp.Ordered]() *SetSmall[T] {
+	s := &SetSmall[T]{}
+
+	// Ensure interface compliance
+	var _ Set[T] = s
+
+	return s
+}
+
+// NewEmpty returns a new empty Set backed by a sorted slice.
+func (s *SetSmall[T]) NewEmpty() Set[T] {
+	return newSmall[T]()
+}
+
+// insert adds item to s, reporting whether it wasn't present yet.
+func (s *SetSmall[T]) insert(item T) bool {
+	i, has := slices.BinarySearch(s.items, item)
+	if !has {
+		s.items = slices.Insert(s.items, i, item)
+	}
+	return !has
+}
+
+// delete removes item from s, reporting whether it was present.
+func (s *SetSmall[T]) delete(item T) bool {
+	i, has := slices.BinarySearch(s.items, item)
+	if has {
+		s.items = slices.Delete(s.items, i, i+1)
+	}
+	return has
+}
+
+// has reports whether item is in s.
+func (s *SetSmall[T]) has(item T) bool {
+	_, has := slices.BinarySearch(s.items, item)
+	return has
+}
+
+// adding calls f, which adds items to s, and then the callbacks registered by
+// OnSizeThreshold whose threshold f crossed.
+func (s *SetSmall[T]) adding(f func()) {
+	before := len(s.items)
+	f()
+	notify(thresholdsCrossed(s.thresholds, before, len(s.items)), len(s.items))
+}
+
+// Add includes the specified items (one or more) to the set. The underlying
+// Set s is modified. If passed nothing it silently returns.
+func (s *SetSmall[T]) Add(items ...T) {
+	before := len(s.items)
+	for _, item := range items {
+		s.insert(item)
+	}
+	notify(thresholdsCrossed(s.thresholds, before, len(s.items)), len(s.items))
+}
+
+// AddAll includes the specified items to the set, like Add, but first grows
+// the set to hold all of them. Items already in the set or passed more than
+// once are counted too, which may over-reserve.
+func (s *SetSmall[T]) AddAll(items ...T) {
+	s.Grow(len(items))
+	s.Add(items...)
+}
+
+// AddNew includes the specified items to the set, like Add, and returns a new
+// set of those which weren't already present. An item passed more than once
+// is new only the first time.
+func (s *SetSmall[T]) AddNew(items ...T) Set[T] {
+	added := newSmall[T]()
+	s.adding(func() {
+		for _, item := range items {
+			if s.insert(item) {
+				added.insert(item)
+			}
+		}
+	})
+	return added
+}
+
+// Intern adds item to the set if it isn't present yet, and returns the stored
+// item of the set equal to it, which is item itself only if it was added.
+func (s *SetSmall[T]) Intern(item T) (stored T) {
+	s.adding(func() {
+		i, has := slices.BinarySearch(s.items, item)
+		if !has {
+			s.items = slices.Insert(s.items, i, item)
+		}
+		stored = s.items[i]
+	})
+	return stored
+}
+
+// Toggle removes each of the specified items which is in the set, and adds
+// each one which isn't. See set.Toggle for details.
+func (s *SetSmall[T]) Toggle(items ...T) {
+	s.adding(func() { s.toggle(items, nil) })
+}
+
+// ToggleReport is like Toggle, and returns whether each item is in the set
+// after toggling it, in the same order as the items.
+func (s *SetSmall[T]) ToggleReport(items ...T) []bool {
+	has := make([]bool, len(items))
+	s.adding(func() { s.toggle(items, has) })
+	return has
+}
+
+// toggle toggles each of items in s, recording whether it's in s afterwards at
+// the same index of has, unless has is nil.
+func (s *SetSmall[T]) toggle(items []T, has []bool) {
+	for i, item := range items {
+		added := !s.delete(item) && s.insert(item)
+		if has != nil {
+			has[i] = added
+		}
+	}
+}
+
+// Remove deletes the specified items from the set.  The underlying Set s is
+// modified. If passed nothing it silently returns.
+func (s *SetSmall[T]) Remove(items ...T) {
+	for _, item := range items {
+		s.delete(item)
+	}
+}
+
+// RemoveReturning deletes the specified items from the set, like Remove, and
+// returns a new set of those which were actually present and removed.
+func (s *SetSmall[T]) RemoveReturning(items ...T) Set[T] {
+	removed := newSmall[T]()
+	for _, item := range items {
+		if s.delete(item) {
+			removed.insert(item)
+		}
+	}
+	return removed
+}
+
+// RemoveReport deletes the specified items from the set, like Remove, and
+// returns those which were removed and those which weren't present. See
+// set.RemoveReport for details.
+func (s *SetSmall[T]) RemoveReport(items ...T) (removed, notFound []T) {
+	for _, item := range items {
+		if s.delete(item) {
+			removed = append(removed, item)
+		} else {
+			notFound = append(notFound, item)
+		}
+	}
+	return removed, notFound
+}
+
+// Pop deletes and returns the largest item of the set, which is the cheapest
+// to remove. If the set is empty, the zero value and false are returned.
+func (s *SetSmall[T]) Pop() (T, bool) {
+	if len(s.items) == 0 {
+		var zeroVal T
+		return zeroVal, false
+	}
+
+	item := s.items[len(s.items)-1]
+	s.items = s.items[:len(s.items)-1]
+	return item, true
+}
+
+// PopHint deletes and returns hint if it's in the set, and otherwise the
+// largest item like Pop. Only if the set is empty, the zero value and false
+// are returned.
+func (s *SetSmall[T]) PopHint(hint T) (T, bool) {
+	if s.delete(hint) {
+		return hint, true
+	}
+	return s.Pop()
+}
+
+// PopWhere deletes every item of the set for which pred returns true, and
+// returns them as a new set.
+func (s *SetSmall[T]) PopWhere(pred func(T) bool) Set[T] {
+	popped := newSmall[T]()
+	s.items = slices.DeleteFunc(s.items, func(item T) bool {
+		if pred(item) {
+			popped.items = append(popped.items, item) // still sorted
+			return true
+		}
+		return false
+	})
+	return popped
+}
+
+// Has looks for the existence of items passed. It returns false if nothing is
+// passed. For multiple items it returns true only if all of  the items exist.
+func (s *SetSmall[T]) Has(items ...T) bool {
+	if len(items) == 0 {
+		return false
+	}
+
+	for _, item := range items {
+		if !s.has(item) {
+			return false
+		}
+	}
+	return true
+}
+
+// HasAll is an explicit alias of Has. It returns true only if all of the
+// passed items exist, and false if nothing is passed.
+func (s *SetSmall[T]) HasAll(items ...T) bool {
+	return s.Has(items...)
+}
+
+// HasAny looks for the existence of items passed. It returns false if nothing
+// is passed. For multiple items it returns true if at least one of the items
+// exists.
+func (s *SetSmall[T]) HasAny(items ...T) bool {
+	return slices.ContainsFunc(items, s.has)
+}
+
+// HasEach looks for the existence of each item passed. It returns a slice
+// reporting the membership of each item, in the same order as the items.
+func (s *SetSmall[T]) HasEach(items ...T) []bool {
+	has := make([]bool, len(items))
+	for i, item := range items {
+		has[i] = s.has(item)
+	}
+	return has
+}
+
+// Get looks up item, returning the stored item of the set equal to it and
+// true, or the zero value and false. It's meant for sets used as interning
+// pools, to reuse the stored item instead of an equal one. See set.Get for
+// details.
+func (s *SetSmall[T]) Get(item T) (T, bool) {
+	if i, has := slices.BinarySearch(s.items, item); has {
+		return s.items[i], true
+	}
+	var zeroVal T
+	return zeroVal, false
+}
+
+// Size returns the number of items in a set.
+func (s *SetSmall[T]) Size() int {
+	return len(s.items)
+}
+
+// OnSizeThreshold registers f to be called whenever adding items grows the
+// set from less than n items to n or more. See set.OnSizeThreshold for
+// details.
+func (s *SetSmall[T]) OnSizeThreshold(n int, f func(size int)) {
+	s.thresholds = append(s.thresholds, sizeThreshold{n, f})
+}
+
+// Cap returns the number of items the set can hold without growing its
+// backing slice.
+func (s *SetSmall[T]) Cap() int {
+	return cap(s.items)
+}
+
+// Grow ensures the set can hold n more items without growing its backing
+// slice.
+func (s *SetSmall[T]) Grow(n int) {
+	if n > 0 {
+		s.items = slices.Grow(s.items, n)
+	}
+}
+
+// EstimatedBytes returns a rough estimate of the heap size of the set: the
+// slice header plus its backing array. Memory referenced by the items, like
+// the bytes of a string, isn't included.
+func (s *SetSmall[T]) EstimatedBytes() int {
+	var zero T
+	return 24 + cap(s.items)*int(unsafe.Sizeof(zero))
+}
+
+// Clear removes all items from the set.
+func (s *SetSmall[T]) Clear() {
+	s.items = nil
+}
+
+// Drain removes all items from the set, like Clear, and returns them in
+// ascending order. If the set is empty, an empty slice is returned.
+func (s *SetSmall[T]) Drain() []T {
+	items := s.items
+	s.items = nil
+	if items == nil {
+		return []T{}
+	}
+	return items
+}
+
+// Compact shrinks the backing slice to the current size.
+func (s *SetSmall[T]) Compact() {
+	s.items = slices.Clip(s.items)
+}
+
+// NormalizeInPlace replaces every item of the set with f(item), collapsing
+// items which f maps to the same one. The items are mapped in place and then
+// sorted again.
+func (s *SetSmall[T]) NormalizeInPlace(f func(item T) T) {
+	for i, item := range s.items {
+		s.items[i] = f(item)
+	}
+	slices.SortFunc(s.items, cmp.Compare[T])
+	s.items = slices.CompactFunc(s.items, func(a, b T) bool { return cmp.Compare(a, b) == 0 })
+}
+
+// IsEmpty reports whether the Set is empty.
+func (s *SetSmall[T]) IsEmpty() bool {
+	return len(s.items) == 0
+}
+
+// IsEqual test whether s and t are the same in size and have the same items.
+func (s *SetSmall[T]) IsEqual(t Set[T]) bool {
+	t, unlock := readLocked(t)
+	defer unlock()
+
+	return s.isEqual(t)
+}
+
+// isEqual is IsEqual without locking t, which must be locked already.
+func (s *SetSmall[T]) isEqual(t Set[T]) bool {
+	if len(s.items) != t.Size() {
+		return false
+	}
+
+	equal := true
+	t.Each(func(item T) bool {
+		equal = s.has(item)
+		return equal
+	})
+	return equal
+}
+
+// IsSubset tests whether t is a subset of s.
+func (s *SetSmall[T]) IsSubset(t Set[T]) bool {
+	t, unlock := readLocked(t)
+	defer unlock()
+
+	return s.isSubset(t)
+}
+
+// isSubset is IsSubset without locking t, which must be locked already.
+func (s *SetSmall[T]) isSubset(t Set[T]) (subset bool) {
+	if t.Size() > len(s.items) {
+		return false
+	}
+
+	subset = true
+	t.Each(func(item T) bool {
+		subset = s.has(item)
+		return subset
+	})
+	return subset
+}
+
+// IsSuperset tests whether t is a superset of s.
+func (s *SetSmall[T]) IsSuperset(t Set[T]) bool {
+	return orEmpty(t).IsSubset(s)
+}
+
+// ContainsSet tests whether every item of t is in s. It's the same as
+// s.IsSubset(t), which reads less naturally.
+func (s *SetSmall[T]) ContainsSet(t Set[T]) bool {
+	return s.IsSubset(t)
+}
+
+// ContainsAnySet tests whether any item of t is in s.
+func (s *SetSmall[T]) ContainsAnySet(t Set[T]) bool {
+	t, unlock := readLocked(t)
+	defer unlock()
+
+	return s.containsAnySet(t)
+}
+
+// containsAnySet is ContainsAnySet without locking t, which must be locked
+// already.
+func (s *SetSmall[T]) containsAnySet(t Set[T]) bool {
+	return slices.ContainsFunc(s.items, func(item T) bool { return t.Has(item) })
+}
+
+// Each traverses the items in the Set in ascending order, calling the
+// provided function for each set member. Traversal will continue until all
+// items in the Set have been visited, or if the closure returns false.
+func (s *SetSmall[T]) Each(f func(item T) bool) {
+	eachOf(s.items, f)
+}
+
+// EachSnapshot is like Each, but traverses a snapshot of the items taken at
+// call time, so the closure may modify the set.
+func (s *SetSmall[T]) EachSnapshot(f func(item T) bool) {
+	eachOf(s.List(), f)
+}
+
+// EachErr traverses the items in the Set, calling the provided function for
+// each set member. Traversal stops at the first error returned by the closure,
+// which is then returned. A nil error means all items have been visited.
+func (s *SetSmall[T]) EachErr(f func(item T) error) error {
+	for _, item := range s.items {
+		if err := f(item); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// EachIndexed is like Each, but also passes the index of each item in
+// ascending order, starting at zero, to the closure.
+func (s *SetSmall[T]) EachIndexed(f func(i int, item T) bool) {
+	for i, item := range s.items {
+		if !f(i, item) {
+			break
+		}
+	}
+}
+
+// EachBatch traverses the items in the Set in ascending order, passing them
+// to the closure in batches of up to batchSize items. See set.EachBatch for
+// details.
+func (s *SetSmall[T]) EachBatch(batchSize int, f func(batch []T) bool) {
+	eachBatch(s.Each, batchSize, f)
+}
+
+// EachParallel calls f for each item of a snapshot of the set from workers
+// goroutines at once. See set.EachParallel for details.
+func (s *SetSmall[T]) EachParallel(workers int, f func(item T)) {
+	eachParallel(s.List(), workers, f)
+}
+
+// Iterator returns an iterator over a snapshot of the items of the set, taken
+// at call time.
+func (s *SetSmall[T]) Iterator() *Iterator[T] {
+	return newIterator(s.List())
+}
+
+// Keys returns a sequence of the items of the set in ascending order. It's a
+// live view over the set rather than a copy, so it doesn't allocate; it must
+// not be used while the set is modified.
+func (s *SetSmall[T]) Keys() iter.Seq[T] {
+	return slices.Values(s.items)
+}
+
+// String returns a string representation of s
+func (s *SetSmall[T]) String() string {
+	return "[" + s.StringFunc(", ", nil) + "]"
+}
+
+// StringFunc returns a string representation of s, formatting each item with
+// format and separating them with sep. Unlike String, the result isn't
+// enclosed in square brackets. If format is nil, items are formatted with %v.
+func (s *SetSmall[T]) StringFunc(sep string, format func(T) string) string {
+	if format == nil {
+		format = func(item T) string { return fmt.Sprintf("%v", item) }
+	}
+
+	t := make([]string, 0, len(s.items))
+	for _, item := range s.items {
+		t = append(t, format(item))
+	}
+
+	return strings.Join(t, sep)
+}
+
+// WriteTo writes the items of s to w in ascending order, one per line. See
+// set.WriteTo for details.
+func (s *SetSmall[T]) WriteTo(w io.Writer) (int64, error) {
+	return writeItems(w, s.items)
+}
+
+// Hash returns a hash of the items of s, which is the same as that of a map
+// set with the same items. See set.Hash for details. Unlike there, the hash
+// isn't cached.
+func (s *SetSmall[T]) Hash() uint64 {
+	var sum uint64
+	for _, item := range s.items {
+		sum += hashItem(item)
+	}
+	return sum
+}
+
+// List returns a slice of all items, in ascending order.
+func (s *SetSmall[T]) List() []T {
+	return slices.Clone(s.items)
+}
+
+// Stream returns a channel over which all items of the set are sent, after
+// which the channel is closed. The items are a snapshot taken at call time;
+// later mutations of the set are not reflected.
+func (s *SetSmall[T]) Stream() <-chan T {
+	return stream(s.items)
+}
+
+// Tee returns n channels, over each of which all items of the set are sent,
+// after which the channels are closed. See set.Tee for details.
+func (s *SetSmall[T]) Tee(n int) []<-chan T {
+	return tee(s.List(), n)
+}
+
+// Copy returns a new Set with a copy of s.
+func (s *SetSmall[T]) Copy() Set[T] {
+	return s.CopyWithCap(0)
+}
+
+// CopyWithCap returns a new Set with a copy of s, like Copy, with room for
+// extra more items reserved. extra is a hint, not a limit: the copy grows
+// past it as needed.
+func (s *SetSmall[T]) CopyWithCap(extra int) Set[T] {
+	u := newSmall[T]()
+	u.items = make([]T, len(s.items), len(s.items)+max(extra, 0))
+	copy(u.items, s.items)
+	return u
+}
+
+// Snapshot returns a read-only copy of s, which panics if modified. Unlike a
+// Copy, it's safe for concurrent reads without locking. It's backed by a map
+// like any frozen set.
+func (s *SetSmall[T]) Snapshot() Set[T] {
+	return newFrozen(s.toMap())
+}
+
+// toMap returns a new map holding the items of s.
+func (s *SetSmall[T]) toMap() map[T]T {
+	m := make(map[T]T, len(s.items))
+	for _, item := range s.items {
+		m[item] = item
+	}
+	return m
+}
+
+// SplitN partitions the items of s into n new sets, e.g. to process them in
+// parallel. Each item ends up in exactly one of the sets, and their sizes
+// differ by at most one. If n is less than one, nil is returned.
+func (s *SetSmall[T]) SplitN(n int) []Set[T] {
+	return splitN(s.items, n, func() Set[T] { return newSmall[T]() })
+}
+
+// AsThreadSafe returns a new thread-safe Set with a copy of s, backed by a
+// map.
+func (s *SetSmall[T]) AsThreadSafe() Set[T] {
+	u := newTS[T]()
+	u.m = s.toMap()
+	return u
+}
+
+// AsNonThreadSafe returns a new non-thread-safe Set with a copy of s, backed
+// by a map.
+func (s *SetSmall[T]) AsNonThreadSafe() Set[T] {
+	u := newNonTS[T]()
+	u.m = s.toMap()
+	return u
+}
+
+// Merge is like Union, however it modifies the current set it's applied on
+// with the given t set.
+func (s *SetSmall[T]) Merge(t Set[T]) {
+	s.Add(orEmpty(t).List()...)
+}
+
+// Separate removes the set items containing in t from set s. Please aware that
+// it's not the opposite of Merge.
+func (s *SetSmall[T]) Separate(t Set[T]) {
+	s.Remove(orEmpty(t).List()...)
+}
+
+// RetainSlice removes the items of s which don't appear in items, leaving the
+// intersection of s and items.
+func (s *SetSmall[T]) RetainSlice(items []T) {
+	keep := make(map[T]struct{}, len(items))
+	for _, item := range items {
+		keep[item] = keyExists
+	}
+
+	s.items = slices.DeleteFunc(s.items, func(item T) bool {
+		_, ok := keep[item]
+		return !ok
+	})
+}
+
+// DifferenceSlice returns a new set which contains the items of s which don't
+// appear in items.
+func (s *SetSmall[T]) DifferenceSlice(items []T) Set[T] {
+	drop := make(map[T]struct{}, len(items))
+	for _, item := range items {
+		drop[item] = keyExists
+	}
+
+	u := newSmall[T]()
+	for _, item := range s.items {
+		if _, ok := drop[item]; !ok {
+			u.items = append(u.items, item) // still sorted
+		}
+	}
+	return u
+}
+
+// IsSubsetSlice tests whether every item of s appears in items. See
+// set.IsSubsetSlice for details.
+func (s *SetSmall[T]) IsSubsetSlice(items []T) bool {
+	found := make(map[T]struct{}, min(len(s.items), len(items)))
+	for _, item := range items {
+		if len(found) == len(s.items) {
+			break
+		}
+		if s.has(item) {
+			found[item] = keyExists
+		}
+	}
+	return len(found) == len(s.items)
+}
+
+// IsSupersetSlice tests whether every one of items is in s. See
+// set.IsSupersetSlice for details.
+func (s *SetSmall[T]) IsSupersetSlice(items []T) bool {
+	return !slices.ContainsFunc(items, func(item T) bool { return !s.has(item) })
+}
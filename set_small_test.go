@@ -0,0 +1,344 @@
+package set
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+	"unsafe"
+)
+
+func TestSetSmall_Add(t *testing.T) {
+	s := newSmall[int]()
+	s.Add(3, 1, 2, 3)
+
+	if s.Size() != 3 || !s.Has(1, 2, 3) || s.Has(4) {
+		t.Error("Add: should hold 1, 2 and 3, got", s)
+	}
+
+	if !reflect.DeepEqual(s.List(), []int{1, 2, 3}) {
+		t.Error("Add: items should be kept in ascending order, got", s.List())
+	}
+
+	added := s.AddNew(2, 4, 4)
+	if !reflect.DeepEqual(added.List(), []int{4}) || s.Size() != 4 {
+		t.Error("AddNew: only 4 should be new, got", added)
+	}
+}
+
+func TestSetSmall_Remove(t *testing.T) {
+	s := newSmall[int]()
+	s.Add(1, 2, 3, 4)
+	s.Remove(2, 5)
+
+	if !reflect.DeepEqual(s.List(), []int{1, 3, 4}) {
+		t.Error("Remove: should hold 1, 3 and 4, got", s)
+	}
+
+	removed := s.RemoveReturning(3, 5)
+	if !reflect.DeepEqual(removed.List(), []int{3}) || s.Has(3) {
+		t.Error("RemoveReturning: only 3 should be removed, got", removed)
+	}
+}
+
+func TestSetSmall_Pop(t *testing.T) {
+	s := newSmall[string]()
+	s.Add("a", "c", "b")
+
+	if item, ok := s.Pop(); !ok || item != "c" || s.Size() != 2 {
+		t.Error("Pop: should pop the largest item, got", item, ok)
+	}
+
+	popped := s.PopWhere(func(item string) bool { return item == "a" })
+	if !reflect.DeepEqual(popped.List(), []string{"a"}) || !reflect.DeepEqual(s.List(), []string{"b"}) {
+		t.Error("PopWhere: should pop a only, got", popped, s)
+	}
+
+	s.Clear()
+	if _, ok := s.Pop(); ok {
+		t.Error("Pop: an empty set has nothing to pop")
+	}
+}
+
+func TestSetSmall_PopHint(t *testing.T) {
+	s := newSmall[int]()
+	s.Add(1, 2, 3)
+
+	if item, ok := s.PopHint(2); !ok || item != 2 || s.Has(2) {
+		t.Error("PopHint: should pop the hint as it's in the set, got", item, ok)
+	}
+
+	item, ok := s.PopHint(4)
+	if !ok || (item != 1 && item != 3) || s.Size() != 1 || s.Has(item) {
+		t.Error("PopHint: should pop another item as the hint isn't in the set, got", item, ok)
+	}
+
+	s.Pop()
+	if _, ok := s.PopHint(1); ok {
+		t.Error("PopHint: an empty set has nothing to pop")
+	}
+}
+
+func TestSetSmall_Toggle(t *testing.T) {
+	s := newSmall[int]()
+	s.Add(1, 2)
+
+	s.Toggle(2, 3)
+	if s.Size() != 2 || !s.Has(1, 3) {
+		t.Error("Toggle: 2 should be removed and 3 added, got", s)
+	}
+
+	has := s.ToggleReport(1, 4, 4, 5)
+	if !reflect.DeepEqual(has, []bool{false, true, false, true}) {
+		t.Error("ToggleReport: wrong membership after toggling, got", has)
+	}
+	if s.Size() != 2 || !s.Has(3, 5) {
+		t.Error("ToggleReport: 1 should be removed, 4 toggled back and 5 added, got", s)
+	}
+}
+
+func TestSetSmall_Drain(t *testing.T) {
+	s := newSmall[int]()
+	s.Add(1, 2, 3)
+
+	items := s.Drain()
+	sort.Ints(items)
+	if !reflect.DeepEqual(items, []int{1, 2, 3}) || !s.IsEmpty() {
+		t.Error("Drain: should remove and return all items, got", items, s)
+	}
+
+	if items := s.Drain(); items == nil || len(items) != 0 {
+		t.Error("Drain: an empty set should return an empty slice, got", items)
+	}
+}
+
+func TestSetSmall_NormalizeInPlace(t *testing.T) {
+	s := newSmall[string]()
+	s.Add("Go", "GO", "rust", "Zig")
+
+	s.NormalizeInPlace(strings.ToLower)
+	if s.Size() != 3 || !s.Has("go", "rust", "zig") {
+		t.Error("NormalizeInPlace: items should be lowercased and collapsed, got", s)
+	}
+	if !reflect.DeepEqual(s.List(), []string{"go", "rust", "zig"}) {
+		t.Error("NormalizeInPlace: items should be sorted again, got", s.List())
+	}
+}
+
+func TestSetSmall_IsSubsetSlice(t *testing.T) {
+	s := newSmall[string]()
+	s.Add("1", "2")
+
+	if !s.IsSubsetSlice([]string{"2", "1", "1", "3"}) {
+		t.Error("IsSubsetSlice: every item of the set is in the slice")
+	}
+	if s.IsSubsetSlice([]string{"1", "1", "3"}) {
+		t.Error("IsSubsetSlice: 2 is not in the slice")
+	}
+
+	if !s.IsSupersetSlice([]string{"2", "2", "1"}) || !s.IsSupersetSlice(nil) {
+		t.Error("IsSupersetSlice: every item of the slice is in the set")
+	}
+	if s.IsSupersetSlice([]string{"1", "3"}) {
+		t.Error("IsSupersetSlice: 3 is not in the set")
+	}
+
+	if !newSmall[string]().IsSubsetSlice(nil) {
+		t.Error("IsSubsetSlice: an empty set is a subset of an empty slice")
+	}
+}
+
+func TestSetSmall_Get(t *testing.T) {
+	s := newSmall[string]()
+	stored := strings.Repeat("go", 2)
+	s.Add(stored)
+
+	item, ok := s.Get("gogo")
+	if !ok || item != "gogo" {
+		t.Error("Get: should find gogo, got", item, ok)
+	}
+	if unsafe.StringData(item) != unsafe.StringData(stored) {
+		t.Error("Get: should return the stored item rather than the argument")
+	}
+
+	if _, ok := s.Get("go"); ok {
+		t.Error("Get: should not find go")
+	}
+}
+
+func TestSetSmall_Intern(t *testing.T) {
+	s := newSmall[string]()
+	first := strings.Repeat("go", 2)
+
+	if item := s.Intern(first); unsafe.StringData(item) != unsafe.StringData(first) {
+		t.Error("Intern: should return the item itself when adding it")
+	}
+	if item := s.Intern(strings.Repeat("go", 2)); unsafe.StringData(item) != unsafe.StringData(first) || s.Size() != 1 {
+		t.Error("Intern: should return the stored item rather than the argument")
+	}
+}
+
+func TestSetSmall_RemoveReport(t *testing.T) {
+	s := newSmall[int]()
+	s.Add(1, 2, 3)
+
+	removed, notFound := s.RemoveReport(3, 4, 1, 3)
+	if !reflect.DeepEqual(removed, []int{3, 1}) || !reflect.DeepEqual(notFound, []int{4, 3}) {
+		t.Error("RemoveReport: wrong removed and not found items, got", removed, notFound)
+	}
+	if s.Size() != 1 || !s.Has(2) {
+		t.Error("RemoveReport: only 2 should be left, got", s)
+	}
+}
+
+func TestSetSmall_WriteTo(t *testing.T) {
+	s := newSmall[string]()
+	s.Add("a", "b", "c")
+
+	var buf strings.Builder
+	n, err := s.WriteTo(&buf)
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	sort.Strings(lines)
+	if err != nil || n != 6 || !reflect.DeepEqual(lines, []string{"a", "b", "c"}) {
+		t.Error("WriteTo: should write each item on a line, got", n, err, buf.String())
+	}
+
+	w := &failingWriter{left: 1}
+	if n, err := s.WriteTo(w); !errors.Is(err, errWriteFailed) || n != 2 {
+		t.Error("WriteTo: should stop at the first error, got", n, err)
+	}
+}
+
+func TestSetSmall_Compare(t *testing.T) {
+	s := newSmall[int]()
+	s.Add(1, 2, 3)
+	u := newTS[int]()
+	u.Add(3, 2, 1)
+
+	if !s.IsEqual(u) || !u.IsEqual(s) {
+		t.Error("IsEqual: should equal a map set with the same items")
+	}
+
+	if s.Hash() != u.Hash() {
+		t.Error("Hash: should equal the hash of a map set with the same items")
+	}
+
+	u.Remove(1)
+	v := u.Copy()
+	v.Add(1, 4)
+	if !s.IsSubset(u) || !s.IsSuperset(v) || s.IsSubset(v) {
+		t.Error("IsSubset: wrong result against a map set")
+	}
+
+	if !s.ContainsAnySet(u) || s.ContainsAnySet(nil) {
+		t.Error("ContainsAnySet: wrong result against a map set")
+	}
+}
+
+func TestSetSmall_EachBatch(t *testing.T) {
+	s := newSmall[int]()
+	s.Add(1, 2, 3, 4, 5)
+
+	var sizes []int
+	r := newSmall[int]()
+	s.EachBatch(2, func(batch []int) bool {
+		sizes = append(sizes, len(batch))
+		r.Add(batch...)
+		return true
+	})
+	if !reflect.DeepEqual(sizes, []int{2, 2, 1}) || !r.IsEqual(s) {
+		t.Error("EachBatch: should pass every item in batches of 2, got", sizes, r)
+	}
+
+	calls := 0
+	s.EachBatch(2, func(batch []int) bool {
+		calls++
+		return false
+	})
+	if calls != 1 {
+		t.Error("EachBatch: should stop when the closure returns false, got", calls)
+	}
+}
+
+func TestSetSmall_String(t *testing.T) {
+	s := newSmall[int]()
+	s.Add(3, 1, 2)
+
+	if str := s.String(); str != "[1, 2, 3]" {
+		t.Error("String: should be [1, 2, 3], got", str)
+	}
+}
+
+func TestSetSmall_Copy(t *testing.T) {
+	s := newSmall[int]()
+	s.Add(1, 2, 3)
+
+	r := s.CopyWithCap(5)
+	if _, ok := r.(*SetSmall[int]); !ok || !r.IsEqual(s) || r.Cap() != 8 {
+		t.Errorf("CopyWithCap: should be an equal small set with room for 8, got %T %v", r, r)
+	}
+
+	r.Add(4)
+	if s.Has(4) {
+		t.Error("Copy: copy should be independent of the original")
+	}
+
+	for _, r := range []Set[int]{s.Snapshot(), s.AsThreadSafe(), s.AsNonThreadSafe()} {
+		if !r.IsEqual(s) {
+			t.Errorf("%T: should hold the items of s, got %v", r, r)
+		}
+	}
+}
+
+func TestSetSmall_OnSizeThreshold(t *testing.T) {
+	s := newSmall[int]()
+
+	var sizes []int
+	s.OnSizeThreshold(2, func(size int) { sizes = append(sizes, size) })
+
+	s.Add(1)
+	s.Merge(Union[int](NewSmall[int](), s, newNonTS[int]()))
+	s.AddAll(2, 3)
+	s.Remove(1, 2, 3)
+	s.AddNew(4, 5)
+
+	if !reflect.DeepEqual(sizes, []int{3, 2}) {
+		t.Error("OnSizeThreshold: should only fire on growth crossings, got", sizes)
+	}
+}
+
+func benchmarkSmallHas(b *testing.B, s Set[int], n int) {
+	for i := 0; i < n; i++ {
+		s.Add(i)
+	}
+
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		s.Has(i % (2 * n))
+	}
+}
+
+func benchmarkSmallAdd(b *testing.B, newSet func() Set[int], n int) {
+	b.ReportAllocs()
+
+	for i := 0; i < b.N; i++ {
+		s := newSet()
+		for j := 0; j < n; j++ {
+			s.Add(j)
+		}
+	}
+}
+
+func BenchmarkSmall(b *testing.B) {
+	for _, n := range []int{4, 16, 64} {
+		b.Run(fmt.Sprintf("Has/Small/%d", n), func(b *testing.B) { benchmarkSmallHas(b, NewSmall[int](), n) })
+		b.Run(fmt.Sprintf("Has/Map/%d", n), func(b *testing.B) { benchmarkSmallHas(b, newNonTS[int](), n) })
+		b.Run(fmt.Sprintf("Add/Small/%d", n), func(b *testing.B) { benchmarkSmallAdd(b, NewSmall[int], n) })
+		b.Run(fmt.Sprintf("Add/Map/%d", n), func(b *testing.B) {
+			benchmarkSmallAdd(b, func() Set[int] { return newNonTS[int]() }, n)
+		})
+	}
+}
@@ -1,20 +1,128 @@
 package set
 
 import (
+	"errors"
+	"fmt"
+	"io"
+	"math"
 	"reflect"
+	"regexp"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"testing/iotest"
+	"time"
 )
 
+func Test_NewFromChannel(t *testing.T) {
+	ch := make(chan int)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				ch <- j
+			}
+		}()
+	}
+
+	go func() {
+		wg.Wait()
+		close(ch)
+	}()
+
+	s := NewFromChannel(ThreadSafe, ch)
+	if s.Size() != 100 {
+		t.Error("NewFromChannel: the set should have 100 unique items, got", s.Size())
+	}
+}
+
+func Test_NewFromSlices(t *testing.T) {
+	s := NewFromSlices(ThreadSafe, []int{1, 2, 3}, nil, []int{}, []int{3, 4})
+	if s.Size() != 4 || !s.Has(1, 2, 3, 4) {
+		t.Error("NewFromSlices: the set should hold the four distinct items, got", s)
+	}
+
+	if s.Cap() < 5 {
+		t.Error("NewFromSlices: the set should be preallocated to the sum of the lengths, got", s.Cap())
+	}
+}
+
+func Test_NewCap(t *testing.T) {
+	s := NewCap[int](NonThreadSafe, 100)
+	if _, ok := s.(*SetNonTS[int]); !ok {
+		t.Errorf("NewCap: should create a non-thread-safe set, got %T", s)
+	}
+	if !s.IsEmpty() || s.Cap() < 100 {
+		t.Error("NewCap: the set should be empty with room for 100 items, got", s.Size(), s.Cap())
+	}
+
+	u := NewCap[int](ThreadSafe, -1)
+	if _, ok := u.(*SetTS[int]); !ok {
+		t.Errorf("NewCap: should create a thread-safe set, got %T", u)
+	}
+	if !u.IsEmpty() || u.Cap() != 0 {
+		t.Error("NewCap: a negative capacity should behave like New, got", u.Cap())
+	}
+}
+
+func Test_NewFromSyncMap(t *testing.T) {
+	var m sync.Map
+	m.Store("1", 1)
+	m.Store("2", "two")
+	m.Store(3, 3) // not a string key
+
+	s := NewFromSyncMap[string](NonThreadSafe, &m)
+	if s.Size() != 2 || !s.Has("1", "2") {
+		t.Error("NewFromSyncMap: the set should hold the two string keys, got", s)
+	}
+}
+
+func Test_SetIterationSeed(t *testing.T) {
+	SetIterationSeed(42)
+	defer ClearIterationSeed()
+
+	s := newTS[int]()
+	u := newNonTS[int]()
+	for i := 0; i < 100; i++ {
+		s.Add(i)
+		u.Add(99 - i)
+	}
+
+	if !reflect.DeepEqual(s.List(), u.List()) {
+		t.Error("SetIterationSeed: List of equal sets should have the same order")
+	}
+
+	var each []int
+	u.Each(func(item int) bool {
+		each = append(each, item)
+		return true
+	})
+	if !reflect.DeepEqual(each, s.List()) {
+		t.Error("SetIterationSeed: Each should have the same order as List")
+	}
+
+	SetIterationSeed(7)
+	if !reflect.DeepEqual(s.List(), u.List()) {
+		t.Error("SetIterationSeed: List of equal sets should have the same order for another seed")
+	}
+}
+
 func Test_Union(t *testing.T) {
-	s := newTS()
+	s := newTS[any]()
 	s.Add("1", "2", "3")
-	r := newTS()
+	r := newTS[any]()
 	r.Add("3", "4", "5")
-	x := newNonTS()
+	x := newNonTS[any]()
 	x.Add("5", "6", "7")
 
-	u := Union(s, r, x)
-	if settype := reflect.TypeOf(u).String(); settype != "*set.Set" {
+	u := Union[any](s, r, x)
+	if settype := reflect.TypeOf(u).String(); settype != "*set.SetTS[interface {}]" {
 		t.Error("Union should derive its set type from the first passed set, got", settype)
 	}
 	if u.Size() != 7 {
@@ -25,25 +133,74 @@ func Test_Union(t *testing.T) {
 		t.Error("Union: merged items are not availabile in the set.")
 	}
 
-	z := Union(x, r)
+	z := Union[any](x, r)
 	if z.Size() != 5 {
 		t.Error("Union: Union of 2 sets doesn't have the proper number of items.")
 	}
-	if settype := reflect.TypeOf(z).String(); settype != "*set.SetNonTS" {
+	if settype := reflect.TypeOf(z).String(); settype != "*set.SetNonTS[interface {}]" {
 		t.Error("Union should derive its set type from the first passed set, got", settype)
 	}
 
 }
 
+func Test_UnionAs(t *testing.T) {
+	s := newNonTS[string]()
+	s.Add("1", "2", "3")
+	r := newNonTS[string]()
+	r.Add("3", "4", "5")
+	x := newTS[string]()
+	x.Add("5", "6", "7")
+
+	u := UnionAs[string](ThreadSafe, s, r, x)
+	if _, ok := u.(*SetTS[string]); !ok {
+		t.Errorf("UnionAs: the set should be of the given type, got %T", u)
+	}
+
+	if !EqualSlice(u, []string{"1", "2", "3", "4", "5", "6", "7"}) {
+		t.Error("UnionAs: the merged set doesn't have all items in it, got", u)
+	}
+
+	if u := UnionAs[string](NonThreadSafe); !u.IsEmpty() {
+		t.Error("UnionAs: the union of no sets should be empty")
+	}
+}
+
+func Test_UnionInto(t *testing.T) {
+	s := newTS[string]()
+	s.Add("1", "2", "3")
+	r := newTS[string]()
+	r.Add("3", "4", "5")
+	x := newNonTS[string]()
+	x.Add("5", "6", "7")
+
+	UnionInto[string](s, r, x, s)
+	if s.Size() != 7 {
+		t.Error("UnionInto: the merged set doesn't have all items in it.")
+	}
+
+	if !s.Has("1", "2", "3", "4", "5", "6", "7") {
+		t.Error("UnionInto: merged items are not availabile in the set.")
+	}
+
+	UnionInto[string](x, r)
+	if x.Size() != 5 {
+		t.Error("UnionInto: Union of 2 sets doesn't have the proper number of items.")
+	}
+
+	if r.Size() != 3 {
+		t.Error("UnionInto: the other sets should not be modified.")
+	}
+}
+
 func Test_Difference(t *testing.T) {
-	s := newTS()
+	s := newTS[any]()
 	s.Add("1", "2", "3")
-	r := newTS()
+	r := newTS[any]()
 	r.Add("3", "4", "5")
-	x := newNonTS()
+	x := newNonTS[any]()
 	x.Add("5", "6", "7")
 
-	u := Difference(s, r, x)
+	u := Difference[any](s, r, x)
 
 	if u.Size() != 2 {
 		t.Error("Difference: the set doesn't have all items in it.")
@@ -53,7 +210,7 @@ func Test_Difference(t *testing.T) {
 		t.Error("Difference: items are not availabile in the set.")
 	}
 
-	y := Difference(r, r)
+	y := Difference[any](r, r)
 	if y.Size() != 0 {
 		t.Error("Difference: size should be zero")
 	}
@@ -61,13 +218,13 @@ func Test_Difference(t *testing.T) {
 }
 
 func Test_Intersection(t *testing.T) {
-	s1 := newTS()
+	s1 := newTS[any]()
 	s1.Add("1", "3", "4", "5")
-	s2 := newTS()
+	s2 := newTS[any]()
 	s2.Add("3", "5", "6")
-	s3 := newTS()
+	s3 := newTS[any]()
 	s3.Add("4", "5", "6", "7")
-	u := Intersection(s1, s2, s3)
+	u := Intersection[any](s1, s2, s3)
 
 	if u.Size() != 1 {
 		t.Error("Intersection: the set doesn't have all items in it.")
@@ -76,14 +233,28 @@ func Test_Intersection(t *testing.T) {
 	if !u.Has("5") {
 		t.Error("Intersection: items after intersection are not availabile in the set.")
 	}
+
+	if s1.Size() != 4 || s2.Size() != 3 || s3.Size() != 4 {
+		t.Error("Intersection: the given sets should not be modified.")
+	}
+
+	x := newNonTS[any]()
+	x.Add("5")
+	v := Intersection[any](s1, x)
+	if settype := reflect.TypeOf(v).String(); settype != "*set.SetTS[interface {}]" {
+		t.Error("Intersection should derive its set type from the first passed set, got", settype)
+	}
+	if v.Size() != 1 || !v.Has("5") {
+		t.Error("Intersection: should hold 5, got", v)
+	}
 }
 
 func Test_Intersection2(t *testing.T) {
-	s1 := newTS()
+	s1 := newTS[any]()
 	s1.Add("1", "3", "4", "5")
-	s2 := newTS()
+	s2 := newTS[any]()
 	s2.Add("5", "6")
-	i := Intersection(s1, s2)
+	i := Intersection[any](s1, s2)
 
 	if i.Size() != 1 {
 		t.Error("Intersection: size should be 1, it was", i.Size())
@@ -94,12 +265,48 @@ func Test_Intersection2(t *testing.T) {
 	}
 }
 
+func Test_DifferenceAs(t *testing.T) {
+	s := newTS[string]()
+	s.Add("1", "2", "3")
+	r := newTS[string]()
+	r.Add("3", "4", "5")
+	x := newNonTS[string]()
+	x.Add("2", "6", "7")
+
+	u := DifferenceAs[string](NonThreadSafe, s, r, x)
+	if _, ok := u.(*SetNonTS[string]); !ok {
+		t.Errorf("DifferenceAs: the set should be of the given type, got %T", u)
+	}
+
+	if !u.IsEqual(Difference[string](s, r, x)) {
+		t.Error("DifferenceAs: the set should be the same as with Difference, got", u)
+	}
+}
+
+func Test_IntersectionAs(t *testing.T) {
+	s1 := newNonTS[string]()
+	s1.Add("1", "3", "4", "5")
+	s2 := newNonTS[string]()
+	s2.Add("3", "5", "6")
+	s3 := newTS[string]()
+	s3.Add("4", "5", "6", "7")
+
+	u := IntersectionAs[string](ThreadSafe, s1, s2, s3)
+	if _, ok := u.(*SetTS[string]); !ok {
+		t.Errorf("IntersectionAs: the set should be of the given type, got %T", u)
+	}
+
+	if !u.IsEqual(Intersection[string](s1, s2, s3)) {
+		t.Error("IntersectionAs: the set should be the same as with Intersection, got", u)
+	}
+}
+
 func Test_SymmetricDifference(t *testing.T) {
-	s := newTS()
+	s := newTS[any]()
 	s.Add("1", "2", "3")
-	r := newTS()
+	r := newTS[any]()
 	r.Add("3", "4", "5")
-	u := SymmetricDifference(s, r)
+	u := SymmetricDifference[any](s, r)
 
 	if u.Size() != 4 {
 		t.Error("SymmetricDifference: the set doesn't have all items in it.")
@@ -110,48 +317,220 @@ func Test_SymmetricDifference(t *testing.T) {
 	}
 }
 
-func Test_StringSlice(t *testing.T) {
-	s := newTS()
-	s.Add("san francisco", "istanbul", 3.14, 1321, "ankara")
-	u := StringSlice(s)
+func Test_SymmetricDifferenceInto(t *testing.T) {
+	for _, dst := range []Set[string]{newTS[string](), newNonTS[string]()} {
+		dst.Add("1", "2", "3")
+		r := newTS[string]()
+		r.Add("3", "4", "5")
+
+		want := SymmetricDifference[string](dst, r)
+		SymmetricDifferenceInto[string](dst, r)
+
+		if !dst.IsEqual(want) {
+			t.Errorf("SymmetricDifferenceInto: expected %s, got %s", want, dst)
+		}
+
+		SymmetricDifferenceInto(dst, dst)
+		if !dst.IsEmpty() {
+			t.Error("SymmetricDifferenceInto: symmetric difference with itself should be empty")
+		}
+	}
+}
+
+func Test_DeepCopy(t *testing.T) {
+	type point struct{ x, y int }
+
+	s := newTS[*point]()
+	p := &point{1, 2}
+	s.Add(p)
+
+	u := DeepCopy[*point](s, func(p *point) *point {
+		c := *p
+		return &c
+	})
+
+	if _, ok := u.(*SetTS[*point]); !ok {
+		t.Error("DeepCopy: the copy should have the same type as the original set")
+	}
+
+	if u.Size() != 1 || u.Has(p) {
+		t.Error("DeepCopy: the copy should hold one cloned pointer")
+	}
+
+	c, _ := u.Pop()
+	if *c != *p {
+		t.Error("DeepCopy: the cloned value should be equal to the original")
+	}
+}
 
-	if len(u) != 3 {
-		t.Error("StringSlice: slice should only have three items")
+func Test_ComparisonLocking(t *testing.T) {
+	// Compare every combination of receiver and argument implementation while
+	// both sets are written to. "go test -race" should detect this if the
+	// argument isn't locked.
+	ctors := []func() Set[int]{
+		func() Set[int] { return newTS[int]() },
+		func() Set[int] { return newNonTS[int]() },
 	}
 
-	for _, item := range u {
-		r := reflect.TypeOf(item)
-		if r.Kind().String() != "string" {
-			t.Error("StringSlice: slice item should be a string")
+	for _, newS := range ctors {
+		for _, newU := range ctors {
+			s, u := newS(), newU()
+			s.Add(1, 2, 3)
+			u.Add(1, 2, 3)
+
+			if !s.IsEqual(u) || !s.IsSubset(u) || !s.IsSuperset(u) {
+				t.Errorf("comparison of %T and %T: equal sets are not reported as such", s, u)
+			}
+
+			// non-thread-safe sets can't be written to concurrently
+			_, sTS := s.(*SetTS[int])
+			_, uTS := u.(*SetTS[int])
+
+			var wg sync.WaitGroup
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for i := 0; i < 100; i++ {
+					if sTS {
+						s.Add(i)
+					}
+					if uTS {
+						u.Add(i)
+					}
+				}
+			}()
+
+			for i := 0; i < 100; i++ {
+				s.IsEqual(u)
+				s.IsSubset(u)
+				s.IsSuperset(u)
+			}
+			wg.Wait()
 		}
 	}
 }
 
-func Test_IntSlice(t *testing.T) {
-	s := newTS()
-	s.Add("san francisco", "istanbul", 3.14, 1321, "ankara", 8876)
-	u := IntSlice(s)
+func Test_Join(t *testing.T) {
+	a := newTS[string]()
+	a.Add("apple", "avocado", "banana", "cherry")
+	b := newNonTS[rune]()
+	b.Add('a', 'b', 'd')
+
+	j := Join[string, rune](a, b,
+		func(s string) rune { return rune(s[0]) },
+		func(r rune) rune { return r },
+	)
 
-	if len(u) != 2 {
-		t.Error("IntSlice: slice should only have two items")
+	want := []Pair[string, rune]{{"apple", 'a'}, {"avocado", 'a'}, {"banana", 'b'}}
+	if !EqualSlice(j, want) {
+		t.Error("Join: the pairs with matching keys are not in the result, got", j)
 	}
+}
+
+func Test_FlatMap(t *testing.T) {
+	s := newTS[int]()
+	s.Add(0, 1, 2, 3)
 
-	for _, item := range u {
-		r := reflect.TypeOf(item)
-		if r.Kind().String() != "int" {
-			t.Error("Intslice: slice item should be a int")
+	u := FlatMap[int](s, func(n int) []int {
+		var divisors []int
+		for i := 1; i <= n; i++ {
+			if n%i == 0 {
+				divisors = append(divisors, i)
+			}
 		}
+		return divisors
+	})
+
+	if !EqualSlice(u, []int{1, 2, 3}) {
+		t.Error("FlatMap: the flattened set should hold 1, 2 and 3, got", u)
 	}
 }
 
-func BenchmarkSetEquality(b *testing.B) {
-	s := newTS()
-	u := newTS()
+func Test_HashFunc(t *testing.T) {
+	s := newTS[int]()
+	s.Add(1, 2, 3)
+	u := newNonTS[int]()
+	u.Add(3, 2, 1)
 
-	for i := 0; i < b.N; i++ {
+	hash := func(n int) uint64 { return uint64(n) * 0x9e3779b97f4a7c15 }
+	if HashFunc[int](s, hash) != HashFunc[int](u, hash) {
+		t.Error("HashFunc: equal sets should have the same hash")
+	}
+}
+
+func Test_IsEqualCachedHash(t *testing.T) {
+	s := newTS[int]()
+	s.Add(1, 2, 3)
+	u := newNonTS[int]()
+	u.Add(1, 2, 4)
+
+	s.Hash()
+	u.Hash()
+	if s.IsEqual(u) || u.IsEqual(s) {
+		t.Error("IsEqual: sets with a different cached hash should not be equal")
+	}
+
+	// the cached hashes must be invalidated by the mutations
+	u.Remove(4)
+	u.Add(3)
+	if !s.IsEqual(u) || !u.IsEqual(s) {
+		t.Error("IsEqual: sets should be equal after a mutation made them so")
+	}
+
+	if s.Hash() != u.Hash() {
+		t.Error("Hash: equal sets should have the same hash after a mutation")
+	}
+}
+
+func Test_HashEqualItems(t *testing.T) {
+	s := newTS[float64]()
+	s.Add(0.0)
+	u := newNonTS[float64]()
+	u.Add(math.Copysign(0, -1))
+
+	// -0.0 == 0.0, though they format differently
+	if s.Hash() != u.Hash() || !s.IsEqual(u) || !u.IsEqual(s) {
+		t.Error("IsEqual: {0} and {-0} should be equal and have the same hash")
+	}
+
+	// a pointer is hashed by its address, not what it points to
+	type point struct{ x, y int }
+	p := &point{1, 2}
+	a := newTS[*point]()
+	a.Add(p)
+	a.Hash()
+	p.x = 3
+	b := newNonTS[*point]()
+	b.Add(p)
+	if a.Hash() != b.Hash() || !a.IsEqual(b) {
+		t.Error("IsEqual: sets of the same pointer should be equal after the pointee changed")
+	}
+
+	// items of an interface type are hashed along with their dynamic type
+	c := newTS[any]()
+	c.Add(1, "1", point{1, 2}, nil)
+	d := newNonTS[any]()
+	d.Add(nil, point{1, 2}, "1", 1)
+	if c.Hash() != d.Hash() || !c.IsEqual(d) {
+		t.Error("Hash: equal sets of mixed types should have the same hash")
+	}
+	e := newNonTS[any]()
+	e.Add(int64(1), "1", point{1, 2}, nil)
+	if c.Hash() == e.Hash() || c.IsEqual(e) {
+		t.Error("Hash: an int and an int64 should be told apart")
+	}
+}
+
+func BenchmarkSetEqualityCachedHash(b *testing.B) {
+	s := newTS[int]()
+	u := newTS[int]()
+
+	for i := 0; i < 100000; i++ {
 		s.Add(i)
-		u.Add(i)
+		u.Add(i + 1)
 	}
+	s.Hash()
+	u.Hash()
 
 	b.ResetTimer()
 
@@ -160,9 +539,302 @@ func BenchmarkSetEquality(b *testing.B) {
 	}
 }
 
-func BenchmarkSubset(b *testing.B) {
-	s := newTS()
-	u := newTS()
+func Test_Frequency(t *testing.T) {
+	s1 := newTS[string]()
+	s1.Add("1", "2", "3")
+	s2 := newNonTS[string]()
+	s2.Add("2", "3")
+	s3 := newTS[string]()
+	s3.Add("3")
+
+	freq := Frequency[string](s1, s2, s3)
+	if want := map[string]int{"1": 1, "2": 2, "3": 3}; !reflect.DeepEqual(freq, want) {
+		t.Errorf("Frequency: expected %v, got %v", want, freq)
+	}
+
+	if freq := Frequency[string](); len(freq) != 0 {
+		t.Error("Frequency: no sets should give an empty map")
+	}
+}
+
+func Test_CommonToAtLeast(t *testing.T) {
+	s1 := newTS[string]()
+	s1.Add("1", "2", "3")
+	s2 := newNonTS[string]()
+	s2.Add("2", "3")
+	s3 := newTS[string]()
+	s3.Add("3", "4")
+
+	tests := []struct {
+		k    int
+		want []string
+	}{
+		{0, []string{"1", "2", "3", "4"}},
+		{1, []string{"1", "2", "3", "4"}},
+		{2, []string{"2", "3"}},
+		{3, []string{"3"}},
+		{4, nil},
+	}
+
+	for _, tt := range tests {
+		u := CommonToAtLeast[string](tt.k, s1, s2, s3)
+		if !EqualSlice(u, tt.want) {
+			t.Errorf("CommonToAtLeast: k = %d should give %v, got %s", tt.k, tt.want, u)
+		}
+	}
+
+	if u := CommonToAtLeast[string](3, s1, s2, s3); !u.IsEqual(Intersection[string](s1, s2, s3)) {
+		t.Error("CommonToAtLeast: k equal to the number of sets should be their intersection")
+	}
+
+	if u := CommonToAtLeast[string](1, s1, s2, s3); !u.IsEqual(Union[string](s1, s2, s3)) {
+		t.Error("CommonToAtLeast: k of one should be their union")
+	}
+
+	if u := CommonToAtLeast[string](1); !u.IsEmpty() {
+		t.Error("CommonToAtLeast: no sets should give an empty set")
+	}
+}
+
+func Test_EqualApprox(t *testing.T) {
+	a := newTS[float64]()
+	a.Add(0.1+0.2, 1, 2.5)
+	b := newNonTS[float64]()
+	b.Add(0.3, 1.0000001, 2.5)
+
+	if !EqualApprox(a, b, 1e-6) {
+		t.Error("EqualApprox: sets within epsilon should be equal")
+	}
+
+	if EqualApprox(a, b, 1e-9) {
+		t.Error("EqualApprox: sets not within epsilon should not be equal")
+	}
+
+	// near-duplicates can't share a counterpart
+	c := newNonTS[float64]()
+	c.Add(1, 1.0000001, 5)
+	d := newNonTS[float64]()
+	d.Add(1, 5, 6)
+	if EqualApprox(c, d, 1e-6) {
+		t.Error("EqualApprox: near-duplicates should each need their own counterpart")
+	}
+
+	e := newNonTS[float64]()
+	e.Add(math.NaN())
+	if EqualApprox(e, e, 1) {
+		t.Error("EqualApprox: NaN should never be equal")
+	}
+}
+
+func Test_AddRange(t *testing.T) {
+	for _, s := range []Set[int]{newTS[int](), newNonTS[int]()} {
+		AddRange(s, -2, 2)
+		if !EqualSlice(s, []int{-2, -1, 0, 1, 2}) {
+			t.Error("AddRange: the set should hold -2 through 2, got", s)
+		}
+
+		AddRange(s, 5, 4)
+		if s.Size() != 5 {
+			t.Error("AddRange: lo > hi should not add anything")
+		}
+
+		AddRange(s, math.MaxInt, math.MaxInt)
+		if !s.Has(math.MaxInt) {
+			t.Error("AddRange: the range should be inclusive")
+		}
+	}
+}
+
+func Test_OnSizeThresholdFunctions(t *testing.T) {
+	other := newNonTS[int]()
+	other.Add(1, 2, 3)
+
+	adds := map[string]func(Set[int]){
+		"UnionInto":               func(s Set[int]) { UnionInto(s, other) },
+		"SymmetricDifferenceInto": func(s Set[int]) { SymmetricDifferenceInto(s, other) },
+		"AddRange":                func(s Set[int]) { AddRange(s, 1, 3) },
+	}
+
+	for name, add := range adds {
+		for _, s := range []Set[int]{newTS[int](), newNonTS[int](), NewAdaptive[int](ThreadSafe), NewAdaptive[int](NonThreadSafe)} {
+			var sizes []int
+			s.OnSizeThreshold(2, func(size int) {
+				sizes = append(sizes, size)
+				s.Has(size) // must not deadlock
+			})
+
+			add(s)
+			if len(sizes) != 1 || sizes[0] < 2 {
+				t.Errorf("%s: should fire the threshold of a %T once, got %v", name, s, sizes)
+			}
+		}
+	}
+}
+
+func Test_RemoveRange(t *testing.T) {
+	for _, s := range []Set[int]{newTS[int](), newNonTS[int]()} {
+		AddRange(s, 0, 9)
+		RemoveRange(s, 2, 7)
+		if !EqualSlice(s, []int{0, 1, 8, 9}) {
+			t.Error("RemoveRange: the set should hold 0, 1, 8 and 9, got", s)
+		}
+
+		RemoveRange(s, 9, 8)
+		if s.Size() != 4 {
+			t.Error("RemoveRange: lo > hi should not remove anything")
+		}
+	}
+}
+
+func Test_CountInRange(t *testing.T) {
+	s := newTS[int]()
+	s.Add(-5, 1, 2, 3, 10, 100)
+
+	tests := []struct {
+		lo, hi, want int
+	}{
+		{1, 3, 3},    // range smaller than the set
+		{-10, 10, 5}, // range larger than the set
+		{4, 9, 0},    // nothing in the range
+		{3, 1, 0},    // empty range
+		{math.MinInt, math.MaxInt, 6},
+	}
+
+	for _, tt := range tests {
+		if got := CountInRange(s, tt.lo, tt.hi); got != tt.want {
+			t.Errorf("CountInRange: [%d, %d] should count %d items, got %d", tt.lo, tt.hi, tt.want, got)
+		}
+	}
+}
+
+func Test_HasAllInRange(t *testing.T) {
+	s := newNonTS[int]()
+	s.Add(-5, 1, 2, 3, 10, 100)
+
+	if !HasAllInRange(s, 1, 3) {
+		t.Error("HasAllInRange: every integer in [1, 3] is in the set")
+	}
+
+	if HasAllInRange(s, 1, 4) {
+		t.Error("HasAllInRange: 4 is not in the set")
+	}
+
+	if HasAllInRange(s, -10, 100) {
+		t.Error("HasAllInRange: the range is larger than the set")
+	}
+
+	if !HasAllInRange(s, 3, 1) {
+		t.Error("HasAllInRange: an empty range should always be present")
+	}
+}
+
+func Test_NilSets(t *testing.T) {
+	// A nil set should behave like an empty one everywhere it's only read.
+	var null Set[int]
+	s := newTS[int]()
+	s.Add(1, 2, 3)
+
+	if u := Union(null, null, null); !u.IsEmpty() {
+		t.Error("Union: the union of nil sets should be empty")
+	}
+	if u := Union[int](s, null); !u.IsEqual(s) {
+		t.Error("Union: a nil set should add nothing")
+	}
+	if u := UnionAs(ThreadSafe, null, Set[int](s)); !u.IsEqual(s) {
+		t.Error("UnionAs: a nil set should add nothing")
+	}
+	if u := Difference[int](s, null, null); !u.IsEqual(s) {
+		t.Error("Difference: a nil set should remove nothing")
+	}
+	if u := Difference[int](null, s); !u.IsEmpty() {
+		t.Error("Difference: the difference of a nil set should be empty")
+	}
+	if u := DifferenceAs[int](NonThreadSafe, s, null); !u.IsEqual(s) {
+		t.Error("DifferenceAs: a nil set should remove nothing")
+	}
+	if u := Intersection[int](s, null); !u.IsEmpty() {
+		t.Error("Intersection: the intersection with a nil set should be empty")
+	}
+	if u := IntersectionAs[int](ThreadSafe, null, s); !u.IsEmpty() {
+		t.Error("IntersectionAs: the intersection with a nil set should be empty")
+	}
+	if u := SymmetricDifference[int](null, s); !u.IsEqual(s) {
+		t.Error("SymmetricDifference: the symmetric difference with a nil set should be the other set")
+	}
+	if !EqualSlice(null, nil) {
+		t.Error("EqualSlice: a nil set should equal an empty slice")
+	}
+	if missing, extra := DiffSlice(null, []int{1}); len(missing) != 1 || len(extra) != 0 {
+		t.Error("DiffSlice: everything should be missing from a nil set")
+	}
+	if u := DeepCopy(null, func(n int) int { return n }); !u.IsEmpty() {
+		t.Error("DeepCopy: the copy of a nil set should be empty")
+	}
+	if u := Join(null, null, func(n int) int { return n }, func(n int) int { return n }); !u.IsEmpty() {
+		t.Error("Join: the join of nil sets should be empty")
+	}
+	if u := FlatMap(null, func(n int) []int { return []int{n} }); !u.IsEmpty() {
+		t.Error("FlatMap: the result for a nil set should be empty")
+	}
+	if HashFunc(null, func(n int) uint64 { return uint64(n) }) != 0 {
+		t.Error("HashFunc: the hash of a nil set should be that of an empty set")
+	}
+	if freq := Frequency[int](s, null); len(freq) != 3 {
+		t.Error("Frequency: a nil set should count nothing")
+	}
+	if u := CommonToAtLeast[int](1, null, s); !u.IsEqual(s) {
+		t.Error("CommonToAtLeast: a nil set should contain nothing")
+	}
+	if !EqualApprox(nil, newNonTS[float64](), 0) {
+		t.Error("EqualApprox: a nil set should equal an empty set")
+	}
+	if CountInRange(null, 0, 10) != 0 || HasAllInRange(null, 0, 10) {
+		t.Error("CountInRange: a nil set should contain nothing")
+	}
+
+	d := newNonTS[int]()
+	UnionInto[int](d, null, s)
+	SymmetricDifferenceInto[int](d, null)
+	if !d.IsEqual(s) {
+		t.Error("UnionInto: a nil set should add nothing")
+	}
+
+	for _, u := range []Set[int]{newTS[int](), newNonTS[int]()} {
+		u.Add(1)
+		if u.IsEqual(null) || !u.IsSubset(null) || u.IsSuperset(null) ||
+			!u.ContainsSet(null) || u.ContainsAnySet(null) {
+			t.Errorf("%T: a nil set should compare like an empty set", u)
+		}
+
+		u.Merge(null)
+		u.Separate(null)
+		if u.Size() != 1 {
+			t.Errorf("%T: merging or separating a nil set should do nothing", u)
+		}
+	}
+
+	// a nil set which would be modified panics instead
+	panics := map[string]func(){
+		"UnionInto":               func() { UnionInto(null, Set[int](s)) },
+		"SymmetricDifferenceInto": func() { SymmetricDifferenceInto(null, Set[int](s)) },
+		"AddRange":                func() { AddRange(null, 0, 1) },
+		"RemoveRange":             func() { RemoveRange(null, 0, 1) },
+	}
+	for fn, f := range panics {
+		func() {
+			defer func() {
+				if msg, _ := recover().(string); !strings.Contains(msg, fn+" called with a nil") {
+					t.Errorf("%s: should panic naming the nil parameter, got %q", fn, msg)
+				}
+			}()
+			f()
+		}()
+	}
+}
+
+func BenchmarkSetEquality(b *testing.B) {
+	s := newTS[any]()
+	u := newTS[any]()
 
 	for i := 0; i < b.N; i++ {
 		s.Add(i)
@@ -172,47 +844,1035 @@ func BenchmarkSubset(b *testing.B) {
 	b.ResetTimer()
 
 	for i := 0; i < b.N; i++ {
-		s.IsSubset(u)
+		s.IsEqual(u)
 	}
 }
 
-func benchmarkIntersection(b *testing.B, numberOfItems int) {
-	s1 := newTS()
-	s2 := newTS()
+func BenchmarkSetEqualityLarge(b *testing.B) {
+	s := newTS[int]()
+	u := newTS[int]()
 
-	for i := 0; i < numberOfItems/2; i++ {
-		s1.Add(i)
-	}
-	for i := 0; i < numberOfItems; i++ {
-		s2.Add(i)
+	for i := 0; i < 100000; i++ {
+		s.Add(i)
+		u.Add(i)
 	}
 
 	b.ResetTimer()
+
 	for i := 0; i < b.N; i++ {
-		Intersection(s1, s2)
+		s.IsEqual(u)
 	}
 }
 
-func BenchmarkIntersection10(b *testing.B) {
-	benchmarkIntersection(b, 10)
+// rLockCountingSet is a lockable set counting how often it's read-locked.
+type rLockCountingSet struct {
+	*SetNonTS[int]
+	sync.RWMutex
+	rLocks atomic.Int32
 }
 
-func BenchmarkIntersection100(b *testing.B) {
-	benchmarkIntersection(b, 100)
+func (s *rLockCountingSet) RLock() {
+	s.rLocks.Add(1)
+	s.RWMutex.RLock()
 }
 
-func BenchmarkIntersection1000(b *testing.B) {
-	benchmarkIntersection(b, 1000)
-}
+func Test_ComparisonLockedOnce(t *testing.T) {
+	ctors := []func() Set[int]{
+		func() Set[int] { return newTS[int]() },
+		func() Set[int] { return newNonTS[int]() },
+		NewSmall[int],
+		func() Set[int] { return NewAdaptive[int](ThreadSafe) },
+	}
 
-func BenchmarkIntersection10000(b *testing.B) {
-	benchmarkIntersection(b, 10000)
-}
+	for _, newS := range ctors {
+		s := newS()
+		s.Add(1, 2, 3)
+		u := &rLockCountingSet{SetNonTS: newNonTS[int]()}
+		u.Add(1, 2, 3)
 
-func BenchmarkIntersection100000(b *testing.B) {
-	benchmarkIntersection(b, 100000)
+		comparisons := map[string]func() bool{
+			"IsEqual":        func() bool { return s.IsEqual(u) },
+			"IsSubset":       func() bool { return s.IsSubset(u) },
+			"ContainsAnySet": func() bool { return s.ContainsAnySet(u) },
+		}
+		for name, compare := range comparisons {
+			u.rLocks.Store(0)
+			if !compare() {
+				t.Errorf("%s of %T: should be true for equal sets", name, s)
+			}
+			if n := u.rLocks.Load(); n != 1 {
+				t.Errorf("%s of %T: the argument should be read-locked once, got %d", name, s, n)
+			}
+		}
+	}
+}
+
+// errWriteFailed is returned by failingWriter.
+var errWriteFailed = errors.New("write failed")
+
+// failingWriter is an io.Writer which accepts left writes, and fails all later
+// ones with errWriteFailed.
+type failingWriter struct {
+	left int
+}
+
+func (w *failingWriter) Write(p []byte) (int, error) {
+	if w.left == 0 {
+		return 0, errWriteFailed
+	}
+	w.left--
+	return len(p), nil
+}
+
+func benchmarkAdd(b *testing.B, add func(s Set[int], items ...int)) {
+	items := make([]int, 100000)
+	for i := range items {
+		items[i] = i
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		add(newTS[int](), items...)
+	}
+}
+
+func BenchmarkUnionLarge(b *testing.B) {
+	s := newTS[int]()
+	u := newTS[int]()
+
+	for i := 0; i < 1000000; i++ {
+		s.Add(i)
+		u.Add(i + 500000)
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		Union[int](s, u)
+	}
+}
+
+func BenchmarkAdd(b *testing.B) {
+	benchmarkAdd(b, func(s Set[int], items ...int) { s.Add(items...) })
+}
+
+func BenchmarkAddAll(b *testing.B) {
+	benchmarkAdd(b, func(s Set[int], items ...int) { s.AddAll(items...) })
+}
+
+func benchmarkBulkAdd(b *testing.B, newSet func() Set[int]) {
+	b.ReportAllocs()
+
+	for i := 0; i < b.N; i++ {
+		s := newSet()
+		for j := 0; j < 1000000; j++ {
+			s.Add(j)
+		}
+	}
+}
+
+func BenchmarkBulkAddNew(b *testing.B) {
+	benchmarkBulkAdd(b, func() Set[int] { return New[int](NonThreadSafe) })
+}
+
+func BenchmarkBulkAddNewCap(b *testing.B) {
+	benchmarkBulkAdd(b, func() Set[int] { return NewCap[int](NonThreadSafe, 1000000) })
+}
+
+func BenchmarkPopEmpty(b *testing.B) {
+	s := newTS[int]()
+
+	for i := 0; i < b.N; i++ {
+		s.Pop()
+	}
+}
+
+func BenchmarkPopEmptyParallel(b *testing.B) {
+	s := newTS[int]()
+
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			s.Pop()
+		}
+	})
+}
+
+func BenchmarkSubset(b *testing.B) {
+	s := newTS[any]()
+	u := newTS[any]()
+
+	for i := 0; i < b.N; i++ {
+		s.Add(i)
+		u.Add(i)
+	}
+
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		s.IsSubset(u)
+	}
+}
+
+func BenchmarkSubsetLarger(b *testing.B) {
+	s := newTS[int]()
+	u := newTS[int]()
+
+	for i := 0; i < 1000; i++ {
+		s.Add(i)
+	}
+	for i := 0; i < 1000000; i++ {
+		u.Add(i)
+	}
+
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		s.IsSubset(u)
+	}
+}
+
+func BenchmarkIntersectionUneven(b *testing.B) {
+	s1 := newTS[int]()
+	s2 := newTS[int]()
+	s3 := newTS[int]()
+
+	for i := 0; i < 1000000; i++ {
+		if i < 10 {
+			s1.Add(i)
+		}
+		if i < 100000 {
+			s2.Add(i)
+		}
+		s3.Add(i)
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		Intersection[int](s3, s2, s1)
+	}
+}
+
+func benchmarkShrunk(b *testing.B, compact bool) {
+	var ms runtime.MemStats
+	for i := 0; i < b.N; i++ {
+		s := newNonTS[int]()
+		for j := 0; j < 1000000; j++ {
+			s.Add(j)
+		}
+		for j := 100; j < 1000000; j++ {
+			s.Remove(j)
+		}
+		if compact {
+			s.Compact()
+		}
+
+		runtime.GC()
+		runtime.ReadMemStats(&ms)
+		runtime.KeepAlive(s)
+	}
+	b.ReportMetric(float64(ms.HeapAlloc), "heap-bytes")
+}
+
+func BenchmarkShrunk(b *testing.B) {
+	benchmarkShrunk(b, false)
+}
+
+func BenchmarkShrunkCompact(b *testing.B) {
+	benchmarkShrunk(b, true)
+}
+
+func benchmarkIntersection(b *testing.B, numberOfItems int) {
+	s1 := newTS[any]()
+	s2 := newTS[any]()
+
+	for i := 0; i < numberOfItems/2; i++ {
+		s1.Add(i)
+	}
+	for i := 0; i < numberOfItems; i++ {
+		s2.Add(i)
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		Intersection[any](s1, s2)
+	}
+}
+
+func BenchmarkIntersection10(b *testing.B) {
+	benchmarkIntersection(b, 10)
+}
+
+func BenchmarkIntersection100(b *testing.B) {
+	benchmarkIntersection(b, 100)
+}
+
+func BenchmarkIntersection1000(b *testing.B) {
+	benchmarkIntersection(b, 1000)
+}
+
+func BenchmarkIntersection10000(b *testing.B) {
+	benchmarkIntersection(b, 10000)
+}
+
+func BenchmarkIntersection100000(b *testing.B) {
+	benchmarkIntersection(b, 100000)
 }
 
 func BenchmarkIntersection1000000(b *testing.B) {
 	benchmarkIntersection(b, 1000000)
 }
+
+func benchmarkIntersectMany(b *testing.B, intersect func(sets ...Set[int]) Set[int]) {
+	// each set lacks a different tenth of the items
+	sets := make([]Set[int], 10)
+	for i := range sets {
+		s := newTS[int]()
+		for j := 0; j < 10000; j++ {
+			if j%10 != i {
+				s.Add(j)
+			}
+		}
+		sets[i] = s
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		intersect(sets...)
+	}
+}
+
+func BenchmarkIntersectionMany(b *testing.B) {
+	benchmarkIntersectMany(b, func(sets ...Set[int]) Set[int] {
+		return Intersection(sets[0], sets[1], sets[2:]...)
+	})
+}
+
+func BenchmarkIntersectAllMany(b *testing.B) {
+	benchmarkIntersectMany(b, IntersectAll[int])
+}
+
+func Test_EqualSlice(t *testing.T) {
+	s := newTS[string]()
+	s.Add("1", "2", "3")
+
+	if !EqualSlice[string](s, []string{"3", "1", "2", "1"}) {
+		t.Error("EqualSlice: set and slice have the same distinct items. However it returns false")
+	}
+
+	if EqualSlice[string](s, []string{"1", "2"}) {
+		t.Error("EqualSlice: set has an extra item. However it returns true")
+	}
+
+	if EqualSlice[string](s, []string{"1", "2", "3", "4"}) {
+		t.Error("EqualSlice: slice has an extra item. However it returns true")
+	}
+
+	if !EqualSlice[string](newNonTS[string](), nil) {
+		t.Error("EqualSlice: empty set and nil slice should be equal")
+	}
+}
+
+func Test_DiffSlice(t *testing.T) {
+	s := newNonTS[string]()
+	s.Add("1", "2", "3")
+
+	missing, extra := DiffSlice[string](s, []string{"2", "3", "4", "4"})
+	if !reflect.DeepEqual(missing, []string{"4"}) {
+		t.Error("DiffSlice: missing should only contain 4, got", missing)
+	}
+	if !reflect.DeepEqual(extra, []string{"1"}) {
+		t.Error("DiffSlice: extra should only contain 1, got", extra)
+	}
+}
+
+func Test_IntersectsAtLeast(t *testing.T) {
+	a := newTS[int]()
+	a.Add(1, 2, 3, 4, 5)
+	b := newNonTS[int]()
+	b.Add(3, 4, 5, 6)
+
+	if !IntersectsAtLeast[int](a, b, 3) {
+		t.Error("IntersectsAtLeast: a and b share 3 items")
+	}
+	if IntersectsAtLeast[int](a, b, 4) {
+		t.Error("IntersectsAtLeast: a and b share only 3 items")
+	}
+	if !IntersectsAtLeast[int](a, a, 5) || IntersectsAtLeast[int](a, a, 6) {
+		t.Error("IntersectsAtLeast: a shares all its items with itself")
+	}
+	if !IntersectsAtLeast[int](a, nil, 0) || IntersectsAtLeast[int](a, nil, 1) {
+		t.Error("IntersectsAtLeast: a nil set shares nothing")
+	}
+}
+
+func Test_Sum(t *testing.T) {
+	a := newTS[int]()
+	a.Add(1, 2, 3, 4)
+	if sum := Sum[int](a); sum != 10 {
+		t.Error("Sum: should be 10, got", sum)
+	}
+
+	b := newNonTS[float64]()
+	b.Add(0.5, 1.5)
+	if sum := Sum[float64](b); sum != 2 {
+		t.Error("Sum: should be 2, got", sum)
+	}
+
+	if sum := Sum[int](newNonTS[int]()); sum != 0 {
+		t.Error("Sum: an empty set should sum to 0, got", sum)
+	}
+	if sum := Sum[int](nil); sum != 0 {
+		t.Error("Sum: a nil set should sum to 0, got", sum)
+	}
+}
+
+func Test_Mean(t *testing.T) {
+	a := newTS[int]()
+	a.Add(1, 2, 3, 6)
+	if mean, ok := Mean[int](a); !ok || mean != 3 {
+		t.Error("Mean: should be 3, got", mean, ok)
+	}
+
+	b := newNonTS[int]()
+	b.Add(7)
+	if mean, ok := Mean[int](b); !ok || mean != 7 {
+		t.Error("Mean: a single item should be its own mean, got", mean, ok)
+	}
+
+	if _, ok := Mean[int](newNonTS[int]()); ok {
+		t.Error("Mean: an empty set has no mean")
+	}
+	if _, ok := Mean[int](nil); ok {
+		t.Error("Mean: a nil set has no mean")
+	}
+}
+
+func Test_MinMax(t *testing.T) {
+	a := newTS[string]()
+	a.Add("b", "d", "a", "c")
+	if min, max, ok := MinMax[string](a); !ok || min != "a" || max != "d" {
+		t.Error("MinMax: should be a and d, got", min, max, ok)
+	}
+
+	b := newNonTS[int]()
+	b.Add(-3)
+	if min, max, ok := MinMax[int](b); !ok || min != -3 || max != -3 {
+		t.Error("MinMax: a single item should be both min and max, got", min, max, ok)
+	}
+
+	if _, _, ok := MinMax[int](newNonTS[int]()); ok {
+		t.Error("MinMax: an empty set has no min or max")
+	}
+	if _, _, ok := MinMax[int](nil); ok {
+		t.Error("MinMax: a nil set has no min or max")
+	}
+}
+
+func Test_Compare(t *testing.T) {
+	a := newTS[int]()
+	a.Add(1, 2, 3)
+	b := newNonTS[int]()
+	b.Add(2, 3, 4, 5)
+
+	c := Compare[int](a, b)
+	if _, ok := c.InBoth.(*SetTS[int]); !ok {
+		t.Errorf("Compare: results should be of the type of a, got %T", c.InBoth)
+	}
+
+	if !EqualSlice(c.OnlyInA, []int{1}) || !EqualSlice(c.OnlyInB, []int{4, 5}) || !EqualSlice(c.InBoth, []int{2, 3}) {
+		t.Error("Compare: wrong split, got", c.OnlyInA, c.OnlyInB, c.InBoth)
+	}
+
+	c = Compare[int](a, a)
+	if !c.OnlyInA.IsEmpty() || !c.OnlyInB.IsEmpty() || !c.InBoth.IsEqual(a) {
+		t.Error("Compare: a set should only share items with itself, got", c.OnlyInA, c.OnlyInB, c.InBoth)
+	}
+
+	c = Compare[int](nil, b)
+	if !c.OnlyInA.IsEmpty() || !c.OnlyInB.IsEqual(b) || !c.InBoth.IsEmpty() {
+		t.Error("Compare: a nil set should be empty, got", c.OnlyInA, c.OnlyInB, c.InBoth)
+	}
+}
+
+func Test_LazyUnion(t *testing.T) {
+	a := newTS[int]()
+	a.Add(1, 2, 3)
+	b := newNonTS[int]()
+	b.Add(3, 4)
+
+	var items []int
+	for item := range LazyUnion[int](a, b, nil, a) {
+		items = append(items, item)
+	}
+	if len(items) != 4 || !EqualSlice[int](Union[int](a, b), items) {
+		t.Error("LazyUnion: should yield each item of the union once, got", items)
+	}
+
+	n := 0
+	for range LazyUnion[int](a, b) {
+		n++
+		break
+	}
+	if n != 1 {
+		t.Error("LazyUnion: should stop when the loop breaks")
+	}
+
+	for range LazyUnion[int]() {
+		t.Error("LazyUnion: no sets should yield nothing")
+	}
+}
+
+func Test_LazyDifference(t *testing.T) {
+	a := newTS[int]()
+	a.Add(1, 2, 3, 4)
+	b := newNonTS[int]()
+	b.Add(2)
+	c := newTS[int]()
+	c.Add(4, 5)
+
+	var items []int
+	for item := range LazyDifference[int](a, b, c, nil, c) {
+		items = append(items, item)
+	}
+	if !EqualSlice[int](Difference[int](a, b, c), items) {
+		t.Error("LazyDifference: should yield the items in a only, got", items)
+	}
+
+	n := 0
+	for range LazyDifference[int](a) {
+		n++
+		break
+	}
+	if n != 1 {
+		t.Error("LazyDifference: should stop when the loop breaks")
+	}
+
+	for range LazyDifference[int](a, b, a) {
+		t.Error("LazyDifference: a minus itself should yield nothing")
+	}
+	for range LazyDifference[int](nil, b) {
+		t.Error("LazyDifference: a nil set should yield nothing")
+	}
+}
+
+func Test_IntersectAll(t *testing.T) {
+	a := newTS[int]()
+	a.Add(1, 2, 3, 4, 5)
+	b := newNonTS[int]()
+	b.Add(2, 3, 4)
+	c := newTS[int]()
+	c.Add(3, 4, 6)
+
+	u := IntersectAll[int](a, b, c, b)
+	if !EqualSlice(u, []int{3, 4}) {
+		t.Error("IntersectAll: should hold 3 and 4, got", u)
+	}
+	if _, ok := u.(*SetTS[int]); !ok {
+		t.Errorf("IntersectAll: result should be of the first set's type, got %T", u)
+	}
+
+	u = IntersectAll[int](a)
+	if !u.IsEqual(a) || u == Set[int](a) {
+		t.Error("IntersectAll: a single set should be copied, got", u)
+	}
+
+	if u := IntersectAll[int](); u == nil || !u.IsEmpty() {
+		t.Error("IntersectAll: no sets should intersect to an empty set, got", u)
+	}
+
+	if u := IntersectAll[int](a, nil, b); !u.IsEmpty() {
+		t.Error("IntersectAll: a nil set should be empty, got", u)
+	}
+}
+
+func Test_newLike(t *testing.T) {
+	sets := []Set[int]{
+		newTS[int](),
+		newNonTS[int](),
+		NewSmall[int](),
+		NewAdaptive[int](ThreadSafe),
+		&rLockCountingSet{SetNonTS: newNonTS[int]()},
+	}
+
+	for _, s := range sets {
+		s.Add(1)
+		u := newLike(s)
+		if !u.IsEmpty() {
+			t.Errorf("newLike of %T: should be empty, got %v", s, u)
+		}
+
+		want := fmt.Sprintf("%T", s)
+		if _, ok := s.(*rLockCountingSet); ok {
+			want = "*set.SetNonTS[int]" // promoted NewEmpty of the embedded set
+		}
+		if got := fmt.Sprintf("%T", u); got != want {
+			t.Errorf("newLike of %T: should be a %s, got %s", s, want, got)
+		}
+
+		if got := fmt.Sprintf("%T", DeepCopy(s, func(i int) int { return i })); got != want {
+			t.Errorf("DeepCopy of %T: should be a %s, got %s", s, want, got)
+		}
+	}
+
+	if _, ok := newLike(newNonTS[int]().Snapshot()).(*SetNonTS[int]); !ok {
+		t.Error("newLike: a frozen set should get a non-thread-safe set")
+	}
+	if _, ok := newLike[int](nil).(*SetNonTS[int]); !ok {
+		t.Error("newLike: a nil set should get a non-thread-safe set")
+	}
+}
+
+func Test_LockOrderingPairs(t *testing.T) {
+	// Every operation locking two sets at once, run in both directions with
+	// writers pending on both sets, for each pair of thread-safe
+	// implementations. This deadlocks unless the locks are taken in a fixed
+	// order.
+	ctors := []func() Set[int]{
+		func() Set[int] { return newTS[int]() },
+		func() Set[int] { return NewAdaptive[int](ThreadSafe) },
+	}
+
+	for _, newA := range ctors {
+		for _, newB := range ctors {
+			a, b := newA(), newB()
+			a.Add(1, 2, 3)
+			b.Add(3, 4, 5)
+
+			pairOps := []func(x, y Set[int]){
+				func(x, y Set[int]) { x.Merge(y) },
+				func(x, y Set[int]) { x.IsEqual(y) },
+				func(x, y Set[int]) { x.IsSuperset(y) },
+				func(x, y Set[int]) { x.ContainsAnySet(y) },
+				func(x, y Set[int]) { Compare(x, y) },
+				func(x, y Set[int]) { IntersectsAtLeast(x, y, 2) },
+				func(x, y Set[int]) {
+					for range LazyDifference(x, y) {
+					}
+				},
+			}
+			var ops []func()
+			for _, op := range pairOps {
+				ops = append(ops, func() { op(a, b) }, func() { op(b, a) })
+			}
+			ops = append(ops, func() { a.Add(6) }, func() { b.Remove(6) })
+
+			done := make(chan struct{})
+			go func() {
+				defer close(done)
+
+				var wg sync.WaitGroup
+				for _, op := range ops {
+					wg.Add(1)
+					go func(op func()) {
+						defer wg.Done()
+						for i := 0; i < 200; i++ {
+							op()
+						}
+					}(op)
+				}
+				wg.Wait()
+			}()
+
+			select {
+			case <-done:
+			case <-time.After(10 * time.Second):
+				t.Fatalf("%T and %T: operations in both directions deadlocked", a, b)
+			}
+		}
+	}
+}
+
+func Test_rLockAll(t *testing.T) {
+	a, b := newTS[int](), newTS[int]()
+	unlock := rLockAll(&a.l, nil, &b.l, &a.l)
+
+	// both are read-locked once, so a writer can't get in
+	if a.l.TryLock() || b.l.TryLock() {
+		t.Error("rLockAll: every lock should be read-locked")
+	}
+	unlock()
+
+	if !a.l.TryLock() || !b.l.TryLock() {
+		t.Error("rLockAll: every lock should be released")
+	}
+}
+
+func Test_SafeAdd(t *testing.T) {
+	s := newTS[any]()
+
+	if err := SafeAdd(s, 1, "a", [2]int{1, 2}, nil); err != nil {
+		t.Error("SafeAdd: comparable items should be added, got", err)
+	}
+	if s.Size() != 4 {
+		t.Error("SafeAdd: should hold 4 items, got", s)
+	}
+
+	type holder struct{ v any }
+	for _, item := range []any{[]int{1}, map[int]int{}, func() {}, holder{[]int{1}}} {
+		err := SafeAdd(s, 5, item)
+		if !errors.Is(err, ErrUncomparable) {
+			t.Errorf("SafeAdd: %T should be reported as uncomparable, got %v", item, err)
+		}
+	}
+	if s.Has(5) {
+		t.Error("SafeAdd: no item should be added if any is uncomparable")
+	}
+}
+
+func Test_IsHomogeneous(t *testing.T) {
+	s := newTS[any]()
+	if typ, ok := IsHomogeneous[any](s); !ok || typ != nil {
+		t.Error("IsHomogeneous: an empty set should be homogeneous, got", typ, ok)
+	}
+
+	s.Add(1, 2, 3)
+	if typ, ok := IsHomogeneous[any](s); !ok || typ != reflect.TypeOf(0) {
+		t.Error("IsHomogeneous: a set of ints should be homogeneous, got", typ, ok)
+	}
+
+	s.Add(int64(4))
+	if typ, ok := IsHomogeneous[any](s); ok || typ != nil {
+		t.Error("IsHomogeneous: ints mixed with an int64 should not be homogeneous, got", typ, ok)
+	}
+
+	u := newNonTS[any]()
+	u.Add(nil)
+	if typ, ok := IsHomogeneous[any](u); !ok || typ != nil {
+		t.Error("IsHomogeneous: a set of nil should be homogeneous, got", typ, ok)
+	}
+	u.Add("a")
+	if _, ok := IsHomogeneous[any](u); ok {
+		t.Error("IsHomogeneous: nil mixed with a string should not be homogeneous")
+	}
+}
+
+func Test_MapParallel(t *testing.T) {
+	s := newNonTS[int]()
+	for i := 0; i < 100; i++ {
+		s.Add(i)
+	}
+
+	u := MapParallel[int, string](s, 4, func(i int) string { return strconv.Itoa(i % 10) })
+	if _, ok := u.(*SetNonTS[string]); !ok {
+		t.Errorf("MapParallel: result should be of the type of s, got %T", u)
+	}
+	if !EqualSlice(u, []string{"0", "1", "2", "3", "4", "5", "6", "7", "8", "9"}) {
+		t.Error("MapParallel: should hold every result once, got", u)
+	}
+
+	ts := newTS[int]()
+	ts.Add(1, 2)
+	if u := MapParallel[int, int](ts, 2, func(i int) int { return -i }); !EqualSlice(u, []int{-1, -2}) {
+		t.Error("MapParallel: should hold the negated items, got", u)
+	} else if _, ok := u.(*SetTS[int]); !ok {
+		t.Errorf("MapParallel: result should be of the type of s, got %T", u)
+	}
+
+	if u := MapParallel[int, int](nil, 2, func(i int) int { return i }); !u.IsEmpty() {
+		t.Error("MapParallel: a nil set should map to an empty set, got", u)
+	}
+}
+
+func benchmarkMapParallel(b *testing.B, workers int) {
+	s := newNonTS[int]()
+	for i := 0; i < 100; i++ {
+		s.Add(i)
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		MapParallel[int, int](s, workers, func(i int) int {
+			time.Sleep(100 * time.Microsecond) // stands in for expensive work
+			return i
+		})
+	}
+}
+
+func BenchmarkMapParallel1(b *testing.B) {
+	benchmarkMapParallel(b, 1)
+}
+
+func BenchmarkMapParallel8(b *testing.B) {
+	benchmarkMapParallel(b, 8)
+}
+
+func Test_SymmetricParts(t *testing.T) {
+	a := newTS[int]()
+	a.Add(1, 2, 3)
+	b := newNonTS[int]()
+	b.Add(2, 3, 4, 5)
+
+	onlyA, onlyB := SymmetricParts[int](a, b)
+	if !EqualSlice(onlyA, []int{1}) || !EqualSlice(onlyB, []int{4, 5}) {
+		t.Error("SymmetricParts: wrong split, got", onlyA, onlyB)
+	}
+	if _, ok := onlyB.(*SetTS[int]); !ok {
+		t.Errorf("SymmetricParts: results should be of the type of a, got %T", onlyB)
+	}
+	if u := Union(onlyA, onlyB); !u.IsEqual(SymmetricDifference[int](a, b)) {
+		t.Error("SymmetricParts: parts should form the symmetric difference, got", u)
+	}
+
+	onlyA, onlyB = SymmetricParts[int](a, a)
+	if !onlyA.IsEmpty() || !onlyB.IsEmpty() {
+		t.Error("SymmetricParts: a set has no items apart from itself, got", onlyA, onlyB)
+	}
+
+	onlyA, onlyB = SymmetricParts[int](nil, b)
+	if !onlyA.IsEmpty() || !onlyB.IsEqual(b) {
+		t.Error("SymmetricParts: a nil set should be empty, got", onlyA, onlyB)
+	}
+}
+
+func Test_EqualBy(t *testing.T) {
+	type user struct {
+		id   int
+		name string
+	}
+	users := newTS[user]()
+	users.Add(user{1, "a"}, user{2, "b"})
+	ids := newNonTS[int]()
+	ids.Add(1, 2)
+	byID := func(u user) int { return u.id }
+
+	if !EqualBy[user, int](users, ids, byID) {
+		t.Error("EqualBy: users should correspond to their IDs")
+	}
+
+	ids.Add(3)
+	if EqualBy[user, int](users, ids, byID) {
+		t.Error("EqualBy: sets of different sizes should not be equal")
+	}
+
+	users.Add(user{2, "c"}) // collides with user 2
+	if EqualBy[user, int](users, ids, byID) {
+		t.Error("EqualBy: items mapped to the same key should not be equal")
+	}
+
+	if !EqualBy[user, int](nil, nil, byID) {
+		t.Error("EqualBy: nil sets should be equal")
+	}
+}
+
+func Test_EqualCanonical(t *testing.T) {
+	a := newTS[string]()
+	a.Add("Go", "rust")
+	b := newNonTS[string]()
+	b.Add("go", "RUST")
+
+	if a.IsEqual(b) || !EqualCanonical[string](a, b, strings.ToLower) {
+		t.Error("EqualCanonical: should be equal case insensitively only")
+	}
+
+	b.Add("Rust") // collides with "RUST"
+	if !EqualCanonical[string](a, b, strings.ToLower) {
+		t.Error("EqualCanonical: colliding items should count once")
+	}
+
+	b.Add("zig")
+	if EqualCanonical[string](a, b, strings.ToLower) || EqualCanonical[string](b, a, strings.ToLower) {
+		t.Error("EqualCanonical: should not be equal with an extra item")
+	}
+
+	if !EqualCanonical[string](a, a, strings.ToLower) {
+		t.Error("EqualCanonical: a set should be equal to itself")
+	}
+}
+
+func Test_IntersectionWhere(t *testing.T) {
+	a := newTS[int]()
+	a.Add(1, 2, 3, 4, 5, 6)
+	b := newNonTS[int]()
+	b.Add(2, 3, 4, 7)
+	even := func(n int) bool { return n%2 == 0 }
+
+	r := IntersectionWhere[int](a, b, even)
+	if r.Size() != 2 || !r.Has(2, 4) {
+		t.Error("IntersectionWhere: should hold the even items of both sets, got", r)
+	}
+	if _, ok := r.(*SetTS[int]); !ok {
+		t.Errorf("IntersectionWhere: the result should be of the type of a, got %T", r)
+	}
+
+	if r := IntersectionWhere[int](b, a, even); !r.IsEqual(IntersectionWhere[int](a, b, even)) {
+		t.Error("IntersectionWhere: should not depend on the order of the sets, got", r)
+	}
+
+	if r := IntersectionWhere[int](a, a, even); r.Size() != 3 || !r.Has(2, 4, 6) {
+		t.Error("IntersectionWhere: should filter a set intersected with itself, got", r)
+	}
+
+	if r := IntersectionWhere[int](a, nil, even); !r.IsEmpty() {
+		t.Error("IntersectionWhere: a nil set should be treated as empty, got", r)
+	}
+}
+
+func Test_MergeMapped(t *testing.T) {
+	dst := newTS[string]()
+	dst.Add("/root/a")
+	src := newNonTS[string]()
+	src.Add("a", "b", "./b")
+	abs := func(p string) string { return "/root/" + strings.TrimPrefix(p, "./") }
+
+	MergeMapped[string](dst, src, abs)
+	if dst.Size() != 2 || !dst.Has("/root/a", "/root/b") {
+		t.Error("MergeMapped: mapped items should be added once, got", dst)
+	}
+	if src.Size() != 3 {
+		t.Error("MergeMapped: src should not be modified, got", src)
+	}
+
+	MergeMapped[string](dst, dst, strings.ToUpper)
+	if dst.Size() != 4 || !dst.Has("/ROOT/A", "/ROOT/B") {
+		t.Error("MergeMapped: merging a set into itself should add the mapped items, got", dst)
+	}
+
+	MergeMapped[string](dst, nil, abs)
+	if dst.Size() != 4 {
+		t.Error("MergeMapped: a nil src should add nothing, got", dst)
+	}
+}
+
+func Test_ReadFrom(t *testing.T) {
+	src := newNonTS[string]()
+	src.Add("a", "b", "")
+	var buf strings.Builder
+	src.WriteTo(&buf)
+
+	s := newTS[string]()
+	s.Add("stale")
+	n, err := ReadFrom[string](s, strings.NewReader(buf.String()), ParseString)
+	if err != nil || n != int64(buf.Len()) || !s.IsEqual(src) {
+		t.Error("ReadFrom: should read back what WriteTo wrote, got", n, err, s)
+	}
+
+	n, err = ReadFrom[string](s, strings.NewReader("x\r\ny"), ParseString)
+	if err != nil || n != 4 || s.Size() != 2 || !s.Has("x", "y") {
+		t.Error("ReadFrom: should read CRLF lines and a last line without a line ending, got", n, err, s)
+	}
+
+	if n, err := ReadFrom[string](s, strings.NewReader(""), ParseString); err != nil || n != 0 || !s.IsEmpty() {
+		t.Error("ReadFrom: an empty reader should empty the set, got", n, err, s)
+	}
+
+	u := newNonTS[int]()
+	u.Add(1)
+	_, err = ReadFrom[int](u, strings.NewReader("2\nthree\n4\n"), strconv.Atoi)
+	if !errors.Is(err, strconv.ErrSyntax) || !strings.Contains(err.Error(), "line 2") {
+		t.Error("ReadFrom: should report the line which fails to parse, got", err)
+	}
+	if u.Size() != 1 || !u.Has(1) {
+		t.Error("ReadFrom: the set should not be modified on error, got", u)
+	}
+
+	if _, err := ReadFrom[int](u, io.MultiReader(strings.NewReader("5\n"), iotest.ErrReader(errWriteFailed)), strconv.Atoi); !errors.Is(err, errWriteFailed) {
+		t.Error("ReadFrom: should return the error of the reader, got", err)
+	}
+}
+
+func Test_ReadFromConcurrent(t *testing.T) {
+	s := newTS[string]()
+	s.Add("a")
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 1000; i++ {
+			ReadFrom[string](s, strings.NewReader("b\nc\n"), ParseString)
+		}
+	}()
+
+	for {
+		select {
+		case <-done:
+			return
+		default:
+			if s.IsEmpty() {
+				t.Fatal("ReadFrom: a concurrent reader should never see the set empty")
+			}
+		}
+	}
+}
+
+func Test_LengthHistogram(t *testing.T) {
+	s := newTS[string]()
+	s.Add("", "a", "b", "cd", "héllo")
+	want := map[int]int{0: 1, 1: 2, 2: 1, 6: 1}
+	if got := LengthHistogram(s); !reflect.DeepEqual(got, want) {
+		t.Error("LengthHistogram: should be", want, "got", got)
+	}
+
+	if got := LengthHistogram(nil); len(got) != 0 {
+		t.Error("LengthHistogram: a nil set should have an empty histogram, got", got)
+	}
+}
+
+func Test_WithPrefix(t *testing.T) {
+	s := newTS[string]()
+	s.Add("a/b", "a/c", "b/a", "a")
+	got := WithPrefix(s, "a/")
+	if got.Size() != 2 || !got.Has("a/b", "a/c") {
+		t.Error("WithPrefix: should be {a/b, a/c}, got", got)
+	}
+	if _, ok := got.(*SetTS[string]); !ok {
+		t.Error("WithPrefix: should return a set of the type of s, got", got)
+	}
+
+	if got := WithPrefix(s, ""); !got.IsEqual(s) {
+		t.Error("WithPrefix: an empty prefix should match every item, got", got)
+	}
+	if got := WithPrefix(nil, "a"); got == nil || !got.IsEmpty() {
+		t.Error("WithPrefix: a nil set should give an empty set, got", got)
+	}
+}
+
+func Test_WithSuffix(t *testing.T) {
+	s := newNonTS[string]()
+	s.Add("x.go", "y.go", "go.mod", "z.txt")
+	got := WithSuffix(s, ".go")
+	if got.Size() != 2 || !got.Has("x.go", "y.go") {
+		t.Error("WithSuffix: should be {x.go, y.go}, got", got)
+	}
+}
+
+func Test_MatchingRegexp(t *testing.T) {
+	s := newTS[string]()
+	s.Add("v1", "v12", "xv1", "v")
+	if got := MatchingRegexp(s, regexp.MustCompile(`^v\d+$`)); got.Size() != 2 || !got.Has("v1", "v12") {
+		t.Error("MatchingRegexp: should be {v1, v12}, got", got)
+	}
+	if got := MatchingRegexp(s, regexp.MustCompile(`v1`)); got.Size() != 3 {
+		t.Error("MatchingRegexp: an unanchored regexp should match within items, got", got)
+	}
+}
+
+func Test_Buckets(t *testing.T) {
+	s := newTS[int]()
+	s.Add(-11, -10, -1, 0, 5, 9, 10, 25)
+	want := map[int][]int{-20: {-11}, -10: {-10, -1}, 0: {0, 5, 9}, 10: {10}, 20: {25}}
+	if got := Buckets(s, 10); !reflect.DeepEqual(got, want) {
+		t.Error("Buckets: should be", want, "got", got)
+	}
+
+	u := newNonTS[int]()
+	u.Add(math.MinInt, math.MaxInt)
+	want = map[int][]int{math.MinInt: {math.MinInt}, math.MaxInt - math.MaxInt%3: {math.MaxInt}}
+	if got := Buckets(u, 3); !reflect.DeepEqual(got, want) {
+		t.Error("Buckets: should handle the extreme ints, want", want, "got", got)
+	}
+
+	want = map[int][]int{math.MinInt: {math.MinInt}, math.MaxInt: {math.MaxInt}}
+	if got := Buckets(u, math.MaxInt); !reflect.DeepEqual(got, want) {
+		t.Error("Buckets: should not overflow with a huge width, want", want, "got", got)
+	}
+
+	if got := Buckets(s, 0); got != nil {
+		t.Error("Buckets: a width of 0 should return nil, got", got)
+	}
+	if got := Buckets(nil, 10); len(got) != 0 {
+		t.Error("Buckets: a nil set should have no buckets, got", got)
+	}
+}
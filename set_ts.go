@@ -1,6 +1,10 @@
 package set
 
-import "sync"
+import (
+	"io"
+	"iter"
+	"sync"
+)
 
 // SetTS defines a thread safe set data structure.
 type SetTS[T comparable] struct {
@@ -13,7 +17,7 @@ type SetTS[T comparable] struct {
 // size is created.
 func newTS[T comparable]() *SetTS[T] {
 	s := &SetTS[T]{}
-	s.m = make(map[T]struct{})
+	s.m = make(map[T]T)
 
 	// Ensure interface compliance
 	var _ Set[T] = s
@@ -21,6 +25,11 @@ func newTS[T comparable]() *SetTS[T] {
 	return s
 }
 
+// NewEmpty returns a new empty thread-safe Set.
+func (s *SetTS[T]) NewEmpty() Set[T] {
+	return newTS[T]()
+}
+
 // Add includes the specified items (one or more) to the set. The underlying
 // Set s is modified. If passed nothing it silently returns.
 func (s *SetTS[T]) Add(items ...T) {
@@ -28,12 +37,96 @@ func (s *SetTS[T]) Add(items ...T) {
 		return
 	}
 
-	s.l.Lock()
-	defer s.l.Unlock()
+	s.adding(func() {
+		for _, item := range items {
+			s.m[item] = item
+		}
+		s.changed()
+	})
+}
 
-	for _, item := range items {
-		s.m[item] = keyExists
+// adding calls f, which adds items to s, under the write lock. The callbacks
+// registered by OnSizeThreshold whose threshold f crossed are called after the
+// lock is released.
+func (s *SetTS[T]) adding(f func()) {
+	crossed, size := func() ([]func(int), int) {
+		s.l.Lock()
+		defer s.l.Unlock()
+
+		before := len(s.m)
+		f()
+		return s.crossed(before), len(s.m)
+	}()
+	notify(crossed, size)
+}
+
+// addingView calls f with an unlocked view of s, which adds items to it, like
+// adding does.
+func (s *SetTS[T]) addingView(f func(Set[T])) {
+	s.adding(func() { f(&s.set) })
+}
+
+// AddAll includes the specified items to the set, like Add, but first grows
+// the set to hold all of them, all under a single write lock. See set.AddAll
+// for details.
+func (s *SetTS[T]) AddAll(items ...T) {
+	s.adding(func() { s.set.AddAll(items...) })
+}
+
+// AddNew includes the specified items to the set, like Add, and returns a new
+// thread-safe set of those which weren't already present. An item passed more
+// than once is new only the first time. The write lock is held once for the
+// whole operation.
+func (s *SetTS[T]) AddNew(items ...T) Set[T] {
+	added := newTS[T]()
+	s.adding(func() { s.addNewInto(added, items) })
+	return added
+}
+
+// Intern adds item to the set if it isn't present yet, and returns the stored
+// item of the set equal to it. The check and the insertion are done under a
+// single write lock, so concurrent calls with equal items all get the same
+// one. See set.Intern for details.
+func (s *SetTS[T]) Intern(item T) (stored T) {
+	s.adding(func() { stored = s.set.Intern(item) })
+	return stored
+}
+
+// Toggle removes each of the specified items which is in the set, and adds
+// each one which isn't, all under a single write lock. See set.Toggle for
+// details.
+func (s *SetTS[T]) Toggle(items ...T) {
+	s.adding(func() { s.toggle(items, nil) })
+}
+
+// ToggleReport is like Toggle, and returns whether each item is in the set
+// after toggling it, in the same order as the items.
+func (s *SetTS[T]) ToggleReport(items ...T) []bool {
+	has := make([]bool, len(items))
+	s.adding(func() { s.toggle(items, has) })
+	return has
+}
+
+// AddConcurrent spins up the given number of workers, each reading items from
+// in and adding them to the set, and returns once in is closed and drained.
+// Since a set is unordered, the order in which workers add items doesn't
+// matter. If workers is less than one a single worker is used.
+func (s *SetTS[T]) AddConcurrent(in <-chan T, workers int) {
+	if workers < 1 {
+		workers = 1
 	}
+
+	var wg sync.WaitGroup
+	wg.Add(workers)
+	for i := 0; i < workers; i++ {
+		go func() {
+			defer wg.Done()
+			for item := range in {
+				s.Add(item)
+			}
+		}()
+	}
+	wg.Wait()
 }
 
 // Remove deletes the specified items from the set.  The underlying Set s is
@@ -49,22 +142,75 @@ func (s *SetTS[T]) Remove(items ...T) {
 	for _, item := range items {
 		delete(s.m, item)
 	}
+	s.changed()
+}
+
+// RemoveReturning deletes the specified items from the set, like Remove, and
+// returns a new thread-safe set of those which were actually present and
+// removed. The write lock is held once for the whole operation.
+func (s *SetTS[T]) RemoveReturning(items ...T) Set[T] {
+	removed := newTS[T]()
+
+	s.l.Lock()
+	defer s.l.Unlock()
+
+	s.removeInto(removed, items)
+	return removed
+}
+
+// RemoveReport deletes the specified items from the set, like Remove, and
+// returns those which were removed and those which weren't present, all under
+// a single write lock. See set.RemoveReport for details.
+func (s *SetTS[T]) RemoveReport(items ...T) (removed, notFound []T) {
+	s.l.Lock()
+	defer s.l.Unlock()
+
+	return s.set.RemoveReport(items...)
 }
 
 // Pop  deletes and return an item from the set. The underlying Set s is
 // modified. If set is empty, nil is returned.
 func (s *SetTS[T]) Pop() (T, bool) {
-	s.l.RLock()
-	for item := range s.m {
-		s.l.RUnlock()
-		s.l.Lock()
-		delete(s.m, item)
-		s.l.Unlock()
-		return item, true
+	// An empty set only needs the read lock. Items may be added or removed
+	// before the write lock is taken, so set.Pop checks again under it.
+	if s.IsEmpty() {
+		var zeroVal T
+		return zeroVal, false
+	}
+
+	s.l.Lock()
+	defer s.l.Unlock()
+
+	return s.set.Pop()
+}
+
+// PopHint deletes and returns hint if it's in the set, and otherwise an
+// arbitrary item like Pop. Only if the set is empty, the zero value and false
+// are returned. Both are done under a single write lock, so hint is only
+// passed over if it isn't in the set at that point.
+func (s *SetTS[T]) PopHint(hint T) (T, bool) {
+	if s.IsEmpty() {
+		var zeroVal T
+		return zeroVal, false
 	}
-	s.l.RUnlock()
-	var zeroVal T
-	return zeroVal, false
+
+	s.l.Lock()
+	defer s.l.Unlock()
+
+	return s.set.PopHint(hint)
+}
+
+// PopWhere deletes every item of the set for which pred returns true, and
+// returns them as a new thread-safe set. The write lock is held for the whole
+// operation, so pred must not call methods of s.
+func (s *SetTS[T]) PopWhere(pred func(T) bool) Set[T] {
+	popped := newTS[T]()
+
+	s.l.Lock()
+	defer s.l.Unlock()
+
+	s.popWhereInto(popped, pred)
+	return popped
 }
 
 // Has looks for the existence of items passed. It returns false if nothing is
@@ -87,6 +233,57 @@ func (s *SetTS[T]) Has(items ...T) bool {
 	return has
 }
 
+// HasAll is an explicit alias of Has. It returns true only if all of the
+// passed items exist, and false if nothing is passed.
+func (s *SetTS[T]) HasAll(items ...T) bool {
+	return s.Has(items...)
+}
+
+// HasAny looks for the existence of items passed. It returns false if nothing
+// is passed. For multiple items it returns true if at least one of the items
+// exists.
+func (s *SetTS[T]) HasAny(items ...T) bool {
+	s.l.RLock()
+	defer s.l.RUnlock()
+
+	for _, item := range items {
+		if _, has := s.m[item]; has {
+			return true
+		}
+	}
+	return false
+}
+
+// HasEach looks for the existence of each item passed. It returns a slice
+// reporting the membership of each item, in the same order as the items. The
+// read lock is held once for all of the items.
+func (s *SetTS[T]) HasEach(items ...T) []bool {
+	s.l.RLock()
+	defer s.l.RUnlock()
+
+	return s.set.HasEach(items...)
+}
+
+// Get looks up item under the read lock, returning the stored item of the set
+// equal to it and true, or the zero value and false. See set.Get for details.
+func (s *SetTS[T]) Get(item T) (T, bool) {
+	s.l.RLock()
+	defer s.l.RUnlock()
+
+	return s.set.Get(item)
+}
+
+// OnSizeThreshold registers f to be called whenever adding items grows the
+// set from less than n items to n or more. See set.OnSizeThreshold for
+// details. f is called after the write lock is released, so it may call any
+// method of s.
+func (s *SetTS[T]) OnSizeThreshold(n int, f func(size int)) {
+	s.l.Lock()
+	defer s.l.Unlock()
+
+	s.set.OnSizeThreshold(n, f)
+}
+
 // Size returns the number of items in a set.
 func (s *SetTS[T]) Size() int {
 	s.l.RLock()
@@ -96,66 +293,227 @@ func (s *SetTS[T]) Size() int {
 	return l
 }
 
+// IsEmpty reports whether the Set is empty.
+func (s *SetTS[T]) IsEmpty() bool {
+	return s.Size() == 0
+}
+
+// EstimatedBytes returns a rough estimate of the heap size of the set. See
+// estimateBytes for the formula used.
+func (s *SetTS[T]) EstimatedBytes() int {
+	return estimateBytes[T](s.Size())
+}
+
 // Clear removes all items from the set.
 func (s *SetTS[T]) Clear() {
 	s.l.Lock()
 	defer s.l.Unlock()
 
-	s.m = make(map[T]struct{})
+	s.set.Clear()
 }
 
-// IsEqual test whether s and t are the same in size and have the same items.
-func (s *SetTS[T]) IsEqual(t Set[T]) bool {
+// Drain removes all items from the set, like Clear, and returns them, under a
+// single write lock. So no item added concurrently is cleared without being
+// returned, unlike with List followed by Clear. The order of the items is
+// unspecified.
+func (s *SetTS[T]) Drain() []T {
+	s.l.Lock()
+	defer s.l.Unlock()
+
+	return s.set.Drain()
+}
+
+// Compact rebuilds the backing map at the current size. Go maps don't release
+// memory when items are removed, so this reclaims it for a set which has
+// shrunk considerably.
+func (s *SetTS[T]) Compact() {
+	s.l.Lock()
+	defer s.l.Unlock()
+
+	s.set.Compact()
+}
+
+// NormalizeInPlace replaces every item of the set with f(item), collapsing
+// items which f maps to the same one, under a single write lock. See
+// set.NormalizeInPlace for details.
+func (s *SetTS[T]) NormalizeInPlace(f func(item T) T) {
+	s.l.Lock()
+	defer s.l.Unlock()
+
+	s.set.NormalizeInPlace(f)
+}
+
+// Cap returns a best-effort estimate of the number of items the set can hold
+// without growing its backing map. See set.Cap for details.
+func (s *SetTS[T]) Cap() int {
 	s.l.RLock()
 	defer s.l.RUnlock()
 
-	// Force locking only if given set is threadsafe.
-	if conv, ok := t.(*SetTS[T]); ok {
-		conv.l.RLock()
-		defer conv.l.RUnlock()
+	return s.set.Cap()
+}
+
+// Grow ensures the set can hold n more items without growing its backing
+// map. If it can't already, the backing map is rebuilt with room for them.
+func (s *SetTS[T]) Grow(n int) {
+	s.l.Lock()
+	defer s.l.Unlock()
+
+	s.set.Grow(n)
+}
+
+// IsEqual test whether s and t are the same in size and have the same items.
+// If t is lockable it's read-locked as well, in the order described at
+// lockTwo.
+func (s *SetTS[T]) IsEqual(t Set[T]) bool {
+	if t == Set[T](s) {
+		return true
 	}
 
-	// return false if they are no the same size
-	if sameSize := len(s.m) == t.Size(); !sameSize {
-		return false
+	l, view := lockerOf(t)
+	unlock := rLockTwo(&s.l, l)
+	defer unlock()
+
+	return s.set.isEqual(view)
+}
+
+// IsSubset tests whether t is a subset of s. If t is lockable it's read-locked
+// as well.
+func (s *SetTS[T]) IsSubset(t Set[T]) bool {
+	if t == Set[T](s) {
+		return true
 	}
 
-	equal := true
-	t.Each(func(item T) bool {
-		_, equal = s.m[item]
-		return equal // if false, Each() will end
-	})
+	l, view := lockerOf(t)
+	unlock := rLockTwo(&s.l, l)
+	defer unlock()
 
-	return equal
+	return s.set.isSubset(view)
 }
 
-// IsSubset tests whether t is a subset of s.
-func (s *SetTS[T]) IsSubset(t Set[T]) (subset bool) {
-	s.l.RLock()
-	defer s.l.RUnlock()
+// IsSuperset tests whether t is a superset of s.
+func (s *SetTS[T]) IsSuperset(t Set[T]) bool {
+	return orEmpty(t).IsSubset(s)
+}
 
-	subset = true
+// ContainsSet tests whether every item of t is in s. It's the same as
+// s.IsSubset(t), which reads less naturally.
+func (s *SetTS[T]) ContainsSet(t Set[T]) bool {
+	return s.IsSubset(t)
+}
 
-	t.Each(func(item T) bool {
-		_, subset = s.m[item]
-		return subset
-	})
+// ContainsAnySet tests whether any item of t is in s. It iterates the smaller
+// of both sets. If t is lockable it's read-locked as well.
+func (s *SetTS[T]) ContainsAnySet(t Set[T]) bool {
+	if t == Set[T](s) {
+		return !s.IsEmpty()
+	}
 
-	return
+	l, view := lockerOf(t)
+	unlock := rLockTwo(&s.l, l)
+	defer unlock()
+
+	return s.set.containsAnySet(view)
 }
 
 // Each traverses the items in the Set, calling the provided function for each
 // set member. Traversal will continue until all items in the Set have been
 // visited, or if the closure returns false.
+//
+// The read lock is held while calling the closure, so it must not modify s;
+// doing so deadlocks. Use EachSnapshot for a closure which does.
 func (s *SetTS[T]) Each(f func(item T) bool) {
 	s.l.RLock()
 	defer s.l.RUnlock()
 
-	for item := range s.m {
-		if !f(item) {
-			break
-		}
-	}
+	s.set.Each(f)
+}
+
+// EachSnapshot is like Each, but traverses a snapshot of the items taken under
+// the read lock at call time. Unlike Each, the lock isn't held while calling
+// the closure, so it may call any method of s, including ones modifying it.
+// Such modifications aren't reflected in the traversal; this costs allocating
+// the snapshot.
+func (s *SetTS[T]) EachSnapshot(f func(item T) bool) {
+	eachOf(s.List(), f)
+}
+
+// EachErr traverses the items in the Set, calling the provided function for
+// each set member. Traversal stops at the first error returned by the closure,
+// which is then returned. A nil error means all items have been visited.
+// Like with Each, the closure must not modify s.
+func (s *SetTS[T]) EachErr(f func(item T) error) error {
+	s.l.RLock()
+	defer s.l.RUnlock()
+
+	return s.set.EachErr(f)
+}
+
+// EachIndexed is like Each, but also passes a running index, starting at
+// zero, to the closure. The index follows the iteration order, which is
+// unspecified. Like with Each, the closure must not modify s.
+func (s *SetTS[T]) EachIndexed(f func(i int, item T) bool) {
+	s.l.RLock()
+	defer s.l.RUnlock()
+
+	s.set.EachIndexed(f)
+}
+
+// EachBatch traverses the items in the Set like Each, but passes them to the
+// closure in batches of up to batchSize items. See set.EachBatch for details.
+//
+// The read lock is taken once and held while calling the closure, so it must
+// not modify s; doing so deadlocks.
+func (s *SetTS[T]) EachBatch(batchSize int, f func(batch []T) bool) {
+	s.l.RLock()
+	defer s.l.RUnlock()
+
+	s.set.EachBatch(batchSize, f)
+}
+
+// EachParallel calls f for each item of a snapshot of the set, taken under
+// the read lock, from workers goroutines at once. The lock isn't held while
+// calling f. See set.EachParallel for details.
+func (s *SetTS[T]) EachParallel(workers int, f func(item T)) {
+	eachParallel(s.List(), workers, f)
+}
+
+// Iterator returns an iterator over a snapshot of the items of the set, taken
+// under the read lock at call time. The lock isn't held while iterating.
+func (s *SetTS[T]) Iterator() *Iterator[T] {
+	return newIterator(s.List())
+}
+
+// String returns a string representation of s
+func (s *SetTS[T]) String() string {
+	s.l.RLock()
+	defer s.l.RUnlock()
+
+	return s.set.String()
+}
+
+// StringFunc returns a string representation of s, formatting each item with
+// format and separating them with sep. See set.StringFunc for details.
+func (s *SetTS[T]) StringFunc(sep string, format func(T) string) string {
+	s.l.RLock()
+	defer s.l.RUnlock()
+
+	return s.set.StringFunc(sep, format)
+}
+
+// WriteTo writes the items of s to w, one per line. See set.WriteTo for
+// details. The items are snapshotted under the read lock first, and then
+// written with no lock held, so a slow w doesn't block writers of s.
+func (s *SetTS[T]) WriteTo(w io.Writer) (int64, error) {
+	return writeItems(w, s.List())
+}
+
+// Hash returns a hash of the items of s, which is independent of the order
+// they're traversed in. See set.Hash for details.
+func (s *SetTS[T]) Hash() uint64 {
+	s.l.RLock()
+	defer s.l.RUnlock()
+
+	return s.set.Hash()
 }
 
 // List returns a slice of all items. There is also StringSlice() and
@@ -164,32 +522,164 @@ func (s *SetTS[T]) List() []T {
 	s.l.RLock()
 	defer s.l.RUnlock()
 
-	list := make([]T, 0, len(s.m))
+	return s.set.List()
+}
 
-	for item := range s.m {
-		list = append(list, item)
-	}
+// SnapshotList returns the size of the set and a slice of all its items, both
+// under a single read lock. Unlike calling Size and then List, the size always
+// matches the length of the slice, even with concurrent writes.
+func (s *SetTS[T]) SnapshotList() (int, []T) {
+	s.l.RLock()
+	defer s.l.RUnlock()
+
+	return len(s.m), s.set.List()
+}
+
+// Stream returns a channel over which all items of the set are sent, after
+// which the channel is closed. The items are a snapshot taken under the read
+// lock at call time, so a slow consumer doesn't hold the lock and later
+// mutations of the set are not reflected.
+func (s *SetTS[T]) Stream() <-chan T {
+	return stream(s.List())
+}
+
+// Tee returns n channels, over each of which all items of the set are sent,
+// after which the channels are closed. The items are a single snapshot taken
+// under the read lock at call time. See set.Tee for details.
+func (s *SetTS[T]) Tee(n int) []<-chan T {
+	return tee(s.List(), n)
+}
 
-	return list
+// Keys returns a sequence of the items of the set. It's a live view over the
+// set rather than a copy, so ranging over it holds the read lock for the whole
+// loop: the loop body must not modify s, as doing so deadlocks. Use List for
+// a loop which does.
+func (s *SetTS[T]) Keys() iter.Seq[T] {
+	return func(yield func(T) bool) {
+		s.Each(yield)
+	}
 }
 
 // Copy returns a new Set with a copy of s.
 func (s *SetTS[T]) Copy() Set[T] {
+	return s.AsThreadSafe()
+}
+
+// CopyWithCap returns a new thread-safe Set with a copy of s, taken under the
+// read lock, with room for extra more items reserved. extra is a hint, not a
+// limit: the copy grows past it as needed.
+func (s *SetTS[T]) CopyWithCap(extra int) Set[T] {
+	s.l.RLock()
+	defer s.l.RUnlock()
+
 	u := newTS[T]()
-	for item := range s.m {
-		u.Add(item)
-	}
+	s.copyWithCap(&u.set, extra)
 	return u
 }
 
+// Snapshot returns a read-only copy of s, taken under the read lock, which
+// panics if modified. It's safe for concurrent reads without locking, so it
+// can be published to other goroutines.
+func (s *SetTS[T]) Snapshot() Set[T] {
+	s.l.RLock()
+	defer s.l.RUnlock()
+
+	return s.set.Snapshot()
+}
+
+// SplitN partitions the items of s into n new thread-safe sets, e.g. to
+// process them in parallel. The items are snapshotted under the read lock
+// first. Each item ends up in exactly one of the sets, and their sizes differ
+// by at most one. If n is less than one, nil is returned.
+func (s *SetTS[T]) SplitN(n int) []Set[T] {
+	return splitN(s.List(), n, func() Set[T] { return newTS[T]() })
+}
+
+// AsThreadSafe returns a new thread-safe Set with a copy of s.
+func (s *SetTS[T]) AsThreadSafe() Set[T] {
+	s.l.RLock()
+	defer s.l.RUnlock()
+
+	return s.set.AsThreadSafe()
+}
+
+// AsNonThreadSafe returns a new non-thread-safe Set with a copy of s.
+func (s *SetTS[T]) AsNonThreadSafe() Set[T] {
+	s.l.RLock()
+	defer s.l.RUnlock()
+
+	return s.set.AsNonThreadSafe()
+}
+
 // Merge is like Union, however it modifies the current set it's applied on
 // with the given t set.
+//
+// If t is lockable, both are locked at once in the order described at
+// lockTwo, so merging two sets into each other concurrently doesn't deadlock.
 func (s *SetTS[T]) Merge(t Set[T]) {
+	l, view := lockerOf(t)
+	if sameLock(&s.l, l) {
+		return // nothing to add
+	}
+
+	crossed, size := func() ([]func(int), int) {
+		unlock := lockTwo(&s.l, l)
+		defer unlock()
+
+		before := len(s.m)
+		view.Each(func(item T) bool {
+			s.m[item] = item
+			return true
+		})
+		s.changed()
+		return s.crossed(before), len(s.m)
+	}()
+	notify(crossed, size)
+}
+
+// RetainSlice removes the items of s which don't appear in items, leaving the
+// intersection of s and items. The write lock is held once for the whole
+// operation.
+func (s *SetTS[T]) RetainSlice(items []T) {
 	s.l.Lock()
 	defer s.l.Unlock()
 
-	t.Each(func(item T) bool {
-		s.m[item] = keyExists
-		return true
-	})
+	s.set.RetainSlice(items)
+}
+
+// DifferenceSlice returns a new thread-safe set which contains the items of s
+// which don't appear in items.
+func (s *SetTS[T]) DifferenceSlice(items []T) Set[T] {
+	u := newTS[T]()
+
+	s.l.RLock()
+	defer s.l.RUnlock()
+
+	s.differenceSliceInto(u, items)
+	return u
+}
+
+// IsSubsetSlice tests whether every item of s appears in items, under the read
+// lock. See set.IsSubsetSlice for details.
+func (s *SetTS[T]) IsSubsetSlice(items []T) bool {
+	s.l.RLock()
+	defer s.l.RUnlock()
+
+	return s.set.IsSubsetSlice(items)
+}
+
+// IsSupersetSlice tests whether every one of items is in s, under the read
+// lock. See set.IsSupersetSlice for details.
+func (s *SetTS[T]) IsSupersetSlice(items []T) bool {
+	s.l.RLock()
+	defer s.l.RUnlock()
+
+	return s.set.IsSupersetSlice(items)
+}
+
+// Separate removes the set items containing in t from set s. Please aware that
+// it's not the opposite of Merge. The items of t are listed before s is
+// locked, so both are never locked at once and lock ordering doesn't matter.
+func (s *SetTS[T]) Separate(t Set[T]) {
+	s.Remove(orEmpty(t).List()...)
 }
@@ -1,14 +1,22 @@
 package set
 
 import (
+	"errors"
+	"fmt"
+	"io"
 	"reflect"
+	"sort"
 	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
+	"unsafe"
 )
 
 func TestSet_New(t *testing.T) {
-	s := newTS()
+	s := newTS[any]()
 
 	if s.Size() != 0 {
 		t.Error("New: calling without any parameters should create a set with zero size")
@@ -16,7 +24,7 @@ func TestSet_New(t *testing.T) {
 }
 
 func TestSet_New_parameters(t *testing.T) {
-	s := newTS()
+	s := newTS[any]()
 	s.Add("string", "another_string", 1, 3.14)
 
 	if s.Size() != 4 {
@@ -25,7 +33,7 @@ func TestSet_New_parameters(t *testing.T) {
 }
 
 func TestSet_Add(t *testing.T) {
-	s := newTS()
+	s := newTS[any]()
 	s.Add(1)
 	s.Add(2)
 	s.Add(2) // duplicate
@@ -43,7 +51,7 @@ func TestSet_Add(t *testing.T) {
 }
 
 func TestSet_Add_multiple(t *testing.T) {
-	s := newTS()
+	s := newTS[any]()
 	s.Add("ankara", "san francisco", 3.14)
 
 	if s.Size() != 3 {
@@ -55,8 +63,26 @@ func TestSet_Add_multiple(t *testing.T) {
 	}
 }
 
+func TestSet_AddNew(t *testing.T) {
+	s := newTS[string]()
+	s.Add("1", "2")
+
+	added := s.AddNew("2", "3", "4", "3")
+	if !EqualSlice(added, []string{"3", "4"}) {
+		t.Error("AddNew: only the new items should be returned, got", added)
+	}
+
+	if !EqualSlice[string](s, []string{"1", "2", "3", "4"}) {
+		t.Error("AddNew: all items should be added to the set, got", s)
+	}
+
+	if added := s.AddNew(); !added.IsEmpty() {
+		t.Error("AddNew: nothing is passed, the result should be empty")
+	}
+}
+
 func TestSet_Remove(t *testing.T) {
-	s := newTS()
+	s := newTS[any]()
 	s.Add(1)
 	s.Add(2)
 	s.Add("fatih")
@@ -81,7 +107,7 @@ func TestSet_Remove(t *testing.T) {
 }
 
 func TestSet_Remove_multiple(t *testing.T) {
-	s := newTS()
+	s := newTS[any]()
 	s.Add("ankara", "san francisco", 3.14, "istanbul")
 	s.Remove("ankara", "san francisco", 3.14)
 
@@ -94,13 +120,31 @@ func TestSet_Remove_multiple(t *testing.T) {
 	}
 }
 
+func TestSet_RemoveReturning(t *testing.T) {
+	s := newTS[string]()
+	s.Add("1", "2", "3", "4")
+
+	removed := s.RemoveReturning("2", "4", "5")
+	if !EqualSlice(removed, []string{"2", "4"}) {
+		t.Error("RemoveReturning: only the present items should be returned, got", removed)
+	}
+
+	if !EqualSlice[string](s, []string{"1", "3"}) {
+		t.Error("RemoveReturning: the items should be removed from the set, got", s)
+	}
+
+	if removed := s.RemoveReturning(); !removed.IsEmpty() {
+		t.Error("RemoveReturning: nothing is passed, the result should be empty")
+	}
+}
+
 func TestSet_Pop(t *testing.T) {
-	s := newTS()
+	s := newTS[any]()
 	s.Add(1)
 	s.Add(2)
 	s.Add("fatih")
 
-	a := s.Pop()
+	a, _ := s.Pop()
 	if s.Size() != 2 {
 		t.Error("Pop: set size should be two after popping out")
 	}
@@ -111,7 +155,7 @@ func TestSet_Pop(t *testing.T) {
 
 	s.Pop()
 	s.Pop()
-	b := s.Pop()
+	b, _ := s.Pop()
 	if b != nil {
 		t.Error("Pop: should return nil because set is empty")
 	}
@@ -119,8 +163,169 @@ func TestSet_Pop(t *testing.T) {
 	s.Pop() // try to remove something from a zero length set
 }
 
+func TestSet_PopHint(t *testing.T) {
+	s := newTS[int]()
+	s.Add(1, 2, 3)
+
+	if item, ok := s.PopHint(2); !ok || item != 2 || s.Has(2) {
+		t.Error("PopHint: should pop the hint as it's in the set, got", item, ok)
+	}
+
+	item, ok := s.PopHint(4)
+	if !ok || (item != 1 && item != 3) || s.Size() != 1 || s.Has(item) {
+		t.Error("PopHint: should pop another item as the hint isn't in the set, got", item, ok)
+	}
+
+	s.Pop()
+	if _, ok := s.PopHint(1); ok {
+		t.Error("PopHint: an empty set has nothing to pop")
+	}
+}
+
+func TestSet_Toggle(t *testing.T) {
+	s := newTS[int]()
+	s.Add(1, 2)
+
+	s.Toggle(2, 3)
+	if s.Size() != 2 || !s.Has(1, 3) {
+		t.Error("Toggle: 2 should be removed and 3 added, got", s)
+	}
+
+	has := s.ToggleReport(1, 4, 4, 5)
+	if !reflect.DeepEqual(has, []bool{false, true, false, true}) {
+		t.Error("ToggleReport: wrong membership after toggling, got", has)
+	}
+	if s.Size() != 2 || !s.Has(3, 5) {
+		t.Error("ToggleReport: 1 should be removed, 4 toggled back and 5 added, got", s)
+	}
+}
+
+func TestSet_Drain(t *testing.T) {
+	s := newTS[int]()
+	s.Add(1, 2, 3)
+
+	items := s.Drain()
+	sort.Ints(items)
+	if !reflect.DeepEqual(items, []int{1, 2, 3}) || !s.IsEmpty() {
+		t.Error("Drain: should remove and return all items, got", items, s)
+	}
+
+	if items := s.Drain(); items == nil || len(items) != 0 {
+		t.Error("Drain: an empty set should return an empty slice, got", items)
+	}
+}
+
+func TestSet_NormalizeInPlace(t *testing.T) {
+	s := newTS[string]()
+	s.Add("Go", "GO", "rust", "Zig")
+
+	s.NormalizeInPlace(strings.ToLower)
+	if s.Size() != 3 || !s.Has("go", "rust", "zig") {
+		t.Error("NormalizeInPlace: items should be lowercased and collapsed, got", s)
+	}
+}
+
+func TestSet_Get(t *testing.T) {
+	s := newTS[string]()
+	stored := strings.Repeat("go", 2)
+	s.Add(stored, "rust")
+
+	item, ok := s.Get("gogo")
+	if !ok || unsafe.StringData(item) != unsafe.StringData(stored) {
+		t.Error("Get: should return the stored gogo, got", item, ok)
+	}
+
+	if item, ok := s.Get("zig"); ok || item != "" {
+		t.Error("Get: should not find zig, got", item, ok)
+	}
+}
+
+func TestSet_Intern(t *testing.T) {
+	s := newTS[string]()
+
+	// Every goroutine interns the same keys, built anew each time, so each
+	// call must return the instance stored by whichever call added it.
+	const goroutines = 16
+	got := make([][]*byte, goroutines)
+	var wg sync.WaitGroup
+	for g := 0; g < goroutines; g++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			got[g] = make([]*byte, 100)
+			for i := range got[g] {
+				got[g][i] = unsafe.StringData(s.Intern("key" + strconv.Itoa(i%10)))
+			}
+		}()
+	}
+	wg.Wait()
+
+	if s.Size() != 10 {
+		t.Error("Intern: each key should be added once, got", s)
+	}
+	for i := 0; i < 10; i++ {
+		stored, ok := s.Get("key" + strconv.Itoa(i))
+		if !ok {
+			t.Error("Intern: should have added key", i)
+			continue
+		}
+		for g := range got {
+			for j := i; j < len(got[g]); j += 10 {
+				if got[g][j] != unsafe.StringData(stored) {
+					t.Errorf("Intern: goroutine %d got another instance of key%d than the stored one", g, i)
+				}
+			}
+		}
+	}
+}
+
+func TestSet_RemoveReport(t *testing.T) {
+	s := newTS[int]()
+	s.Add(1, 2, 3)
+
+	removed, notFound := s.RemoveReport(3, 4, 1, 3)
+	if !reflect.DeepEqual(removed, []int{3, 1}) || !reflect.DeepEqual(notFound, []int{4, 3}) {
+		t.Error("RemoveReport: wrong removed and not found items, got", removed, notFound)
+	}
+	if s.Size() != 1 || !s.Has(2) {
+		t.Error("RemoveReport: only 2 should be left, got", s)
+	}
+}
+
+func TestSet_WriteTo(t *testing.T) {
+	s := newTS[string]()
+	s.Add("a", "b", "c")
+
+	var buf strings.Builder
+	n, err := s.WriteTo(&buf)
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	sort.Strings(lines)
+	if err != nil || n != 6 || !reflect.DeepEqual(lines, []string{"a", "b", "c"}) {
+		t.Error("WriteTo: should write each item on a line, got", n, err, buf.String())
+	}
+
+	w := &failingWriter{left: 1}
+	if n, err := s.WriteTo(w); !errors.Is(err, errWriteFailed) || n != 2 {
+		t.Error("WriteTo: should stop at the first error, got", n, err)
+	}
+}
+
+func TestSet_PopWhere(t *testing.T) {
+	s := newTS[int]()
+	s.Add(1, 2, 3, 4, 5)
+
+	even := s.PopWhere(func(n int) bool { return n%2 == 0 })
+	if !EqualSlice(even, []int{2, 4}) {
+		t.Error("PopWhere: the matching items should be returned, got", even)
+	}
+
+	if !EqualSlice[int](s, []int{1, 3, 5}) {
+		t.Error("PopWhere: the matching items should be removed from the set, got", s)
+	}
+}
+
 func TestSet_Has(t *testing.T) {
-	s := newTS()
+	s := newTS[any]()
 	s.Add("1", "2", "3", "4")
 
 	if !s.Has("1") {
@@ -132,8 +337,203 @@ func TestSet_Has(t *testing.T) {
 	}
 }
 
+func TestSet_HasAll(t *testing.T) {
+	s := newTS[string]()
+	s.Add("1", "2", "3", "4")
+
+	if !s.HasAll("1", "2", "3", "4") {
+		t.Error("HasAll: the items all exist, but 'HasAll' is returning false")
+	}
+
+	if s.HasAll("1", "5") {
+		t.Error("HasAll: the item 5 doesn't exist, but 'HasAll' is returning true")
+	}
+
+	if s.HasAll() {
+		t.Error("HasAll: nothing is passed, but 'HasAll' is returning true")
+	}
+}
+
+func TestSet_HasAny(t *testing.T) {
+	s := newTS[string]()
+	s.Add("1", "2", "3", "4")
+
+	if !s.HasAny("5", "1") {
+		t.Error("HasAny: the item 1 exists, but 'HasAny' is returning false")
+	}
+
+	if s.HasAny("5", "6") {
+		t.Error("HasAny: none of the items exist, but 'HasAny' is returning true")
+	}
+
+	if s.HasAny() {
+		t.Error("HasAny: nothing is passed, but 'HasAny' is returning true")
+	}
+}
+
+func TestSet_HasEach(t *testing.T) {
+	s := newTS[string]()
+	s.Add("1", "2", "3")
+
+	if has := s.HasEach("1", "5", "3"); !reflect.DeepEqual(has, []bool{true, false, true}) {
+		t.Error("HasEach: membership should be reported per item, got", has)
+	}
+
+	if has := s.HasEach(); len(has) != 0 {
+		t.Error("HasEach: nothing is passed, the result should be empty")
+	}
+}
+
+func TestSet_EachParallel(t *testing.T) {
+	s := newTS[int]()
+	for i := 1; i <= 1000; i++ {
+		s.Add(i)
+	}
+
+	for _, workers := range []int{0, 1, 8, 2000} {
+		var sum, calls atomic.Int64
+		s.EachParallel(workers, func(item int) {
+			sum.Add(int64(item))
+			calls.Add(1)
+		})
+		if sum.Load() != 500500 || calls.Load() != 1000 {
+			t.Errorf("EachParallel with %d workers: every item should be visited once, got %d calls summing to %d", workers, calls.Load(), sum.Load())
+		}
+	}
+
+	newTS[int]().EachParallel(4, func(int) {
+		t.Error("EachParallel: an empty set should visit nothing")
+	})
+}
+
+func TestSet_EachBatch(t *testing.T) {
+	s := newTS[int]()
+	s.Add(1, 2, 3, 4, 5)
+
+	var sizes []int
+	r := newTS[int]()
+	s.EachBatch(2, func(batch []int) bool {
+		sizes = append(sizes, len(batch))
+		r.Add(batch...)
+		return true
+	})
+	if !reflect.DeepEqual(sizes, []int{2, 2, 1}) || !r.IsEqual(s) {
+		t.Error("EachBatch: should pass every item in batches of 2, got", sizes, r)
+	}
+
+	calls := 0
+	s.EachBatch(2, func(batch []int) bool {
+		calls++
+		return false
+	})
+	if calls != 1 {
+		t.Error("EachBatch: should stop when the closure returns false, got", calls)
+	}
+}
+
+func TestSet_Keys(t *testing.T) {
+	s := newTS[int]()
+	s.Add(1, 2, 3)
+
+	r := newTS[int]()
+	for item := range s.Keys() {
+		r.Add(item)
+	}
+	if !r.IsEqual(s) {
+		t.Error("Keys: should yield every item, got", r)
+	}
+
+	n := 0
+	for range s.Keys() {
+		n++
+		break
+	}
+	if n != 1 {
+		t.Error("Keys: should stop when the loop breaks")
+	}
+}
+
+func TestSet_NewEmpty(t *testing.T) {
+	s := newTS[int]()
+	s.Add(1, 2)
+
+	r := s.NewEmpty()
+	if _, ok := r.(*SetTS[int]); !ok || !r.IsEmpty() {
+		t.Errorf("NewEmpty: should return an empty %s, got %T %v", "*SetTS[int]", r, r)
+	}
+}
+
+func TestSet_CopyWithCap(t *testing.T) {
+	s := newTS[int]()
+	s.Add(1, 2, 3)
+
+	r := s.CopyWithCap(5)
+	if _, ok := r.(*SetTS[int]); !ok {
+		t.Errorf("CopyWithCap: should return a %s, got %T", "*SetTS[int]", r)
+	}
+
+	if !r.IsEqual(s) {
+		t.Error("CopyWithCap: copy should equal the original, got", r)
+	}
+
+	if r.Cap() != 8 {
+		t.Error("CopyWithCap: should have room for 8 items, got", r.Cap())
+	}
+
+	r.Add(4, 5, 6, 7, 8, 9) // extra is only a hint
+	if r.Size() != 9 || s.Size() != 3 {
+		t.Error("CopyWithCap: copy should be independent of the original")
+	}
+}
+
+func TestSet_AddAll(t *testing.T) {
+	s := newTS[int]()
+	s.Add(1)
+	s.AddAll(1, 2, 3)
+
+	if s.Size() != 3 || !s.HasAll(1, 2, 3) {
+		t.Error("AddAll: should hold 1, 2 and 3, got", s)
+	}
+
+	if s.Cap() < 4 {
+		t.Error("AddAll: should have reserved room for every item, got", s.Cap())
+	}
+}
+
+func TestSet_OnSizeThreshold(t *testing.T) {
+	s := newTS[int]()
+	s.Add(1)
+
+	var sizes []int
+	s.OnSizeThreshold(3, func(size int) {
+		sizes = append(sizes, size)
+		s.Has(size) // must not deadlock
+	})
+
+	s.Add(2)
+	s.Add(3, 4) // crosses
+	s.Add(5)    // already past the threshold
+	s.Remove(1, 2, 3, 4)
+	s.AddNew(6, 7)        // crosses again
+	s.Merge(newTS[int]()) // doesn't grow
+
+	if !reflect.DeepEqual(sizes, []int{4, 3}) {
+		t.Error("OnSizeThreshold: should only fire on growth crossings, got", sizes)
+	}
+}
+
+func TestSet_EstimatedBytes(t *testing.T) {
+	s := newTS[int64]()
+	empty := s.EstimatedBytes()
+
+	s.Add(1, 2, 3, 4)
+	if got, want := s.EstimatedBytes(), empty+4*17*5/4; got != want {
+		t.Errorf("EstimatedBytes: expected %d bytes for four items, got %d", want, got)
+	}
+}
+
 func TestSet_Clear(t *testing.T) {
-	s := newTS()
+	s := newTS[any]()
 	s.Add(1)
 	s.Add("istanbul")
 	s.Add("san francisco")
@@ -144,8 +544,56 @@ func TestSet_Clear(t *testing.T) {
 	}
 }
 
+func TestSet_Compact(t *testing.T) {
+	s := newTS[int]()
+	for i := 0; i < 1000; i++ {
+		s.Add(i)
+	}
+	for i := 10; i < 1000; i++ {
+		s.Remove(i)
+	}
+
+	s.Compact()
+	if s.Size() != 10 {
+		t.Error("Compact: set size should be ten, got", s.Size())
+	}
+
+	for i := 0; i < 10; i++ {
+		if !s.Has(i) {
+			t.Error("Compact: item is not availabile in the set after compacting", i)
+		}
+	}
+}
+
+func TestSet_Cap(t *testing.T) {
+	s := newTS[int]()
+	s.Add(1, 2, 3)
+	if s.Cap() != 3 {
+		t.Error("Cap: capacity should default to the size, got", s.Cap())
+	}
+
+	s.Grow(10)
+	if s.Cap() != 13 {
+		t.Error("Cap: capacity should be thirteen after growing, got", s.Cap())
+	}
+
+	s.Grow(5) // already has room
+	if s.Cap() != 13 {
+		t.Error("Cap: capacity should not change when there is room, got", s.Cap())
+	}
+
+	if !s.Has(1, 2, 3) || s.Size() != 3 {
+		t.Error("Grow: items should be retained after growing")
+	}
+
+	s.Compact()
+	if s.Cap() != 3 {
+		t.Error("Cap: capacity should be the size after compacting, got", s.Cap())
+	}
+}
+
 func TestSet_IsEmpty(t *testing.T) {
-	s := newTS()
+	s := newTS[any]()
 
 	empty := s.IsEmpty()
 	if !empty {
@@ -163,9 +611,9 @@ func TestSet_IsEmpty(t *testing.T) {
 
 func TestSet_IsEqual(t *testing.T) {
 	// same size, same content
-	s := newTS()
+	s := newTS[any]()
 	s.Add("1", "2", "3")
-	u := newTS()
+	u := newTS[any]()
 	u.Add("1", "2", "3")
 
 	ok := s.IsEqual(u)
@@ -174,9 +622,9 @@ func TestSet_IsEqual(t *testing.T) {
 	}
 
 	// same size, different content
-	a := newTS()
+	a := newTS[any]()
 	a.Add("1", "2", "3")
-	b := newTS()
+	b := newTS[any]()
 	b.Add("4", "5", "6")
 
 	ok = a.IsEqual(b)
@@ -185,9 +633,9 @@ func TestSet_IsEqual(t *testing.T) {
 	}
 
 	// different size, similar content
-	a = newTS()
+	a = newTS[any]()
 	a.Add("1", "2", "3")
-	b = newTS()
+	b = newTS[any]()
 	b.Add("1", "2", "3", "4")
 
 	ok = a.IsEqual(b)
@@ -197,9 +645,9 @@ func TestSet_IsEqual(t *testing.T) {
 }
 
 func TestSet_IsSubset(t *testing.T) {
-	s := newTS()
+	s := newTS[any]()
 	s.Add("1", "2", "3", "4")
-	u := newTS()
+	u := newTS[any]()
 	u.Add("1", "2", "3")
 
 	ok := s.IsSubset(u)
@@ -214,9 +662,9 @@ func TestSet_IsSubset(t *testing.T) {
 }
 
 func TestSet_IsSuperset(t *testing.T) {
-	s := newTS()
+	s := newTS[any]()
 	s.Add("1", "2", "3", "4")
-	u := newTS()
+	u := newTS[any]()
 	u.Add("1", "2", "3")
 
 	ok := u.IsSuperset(s)
@@ -230,8 +678,116 @@ func TestSet_IsSuperset(t *testing.T) {
 	}
 }
 
+func TestSet_EachSnapshot(t *testing.T) {
+	s := newTS[int]()
+	s.Add(1, 2, 3)
+
+	visited := 0
+	s.EachSnapshot(func(item int) bool {
+		visited++
+		s.Add(item * 10) // must not deadlock or be visited
+		return true
+	})
+
+	if visited != 3 {
+		t.Error("EachSnapshot: only the items of the snapshot should be visited, got", visited)
+	}
+
+	if !s.Has(10, 20, 30) {
+		t.Error("EachSnapshot: items added by the closure should be in the set")
+	}
+}
+
+func TestSet_EachErr(t *testing.T) {
+	s := newTS[int]()
+	s.Add(1, 2, 3, 4)
+
+	visited := 0
+	err := s.EachErr(func(item int) error {
+		visited++
+		return nil
+	})
+	if err != nil || visited != 4 {
+		t.Error("EachErr: all items should be visited without an error")
+	}
+
+	errStop := errors.New("stop")
+	visited = 0
+	err = s.EachErr(func(item int) error {
+		visited++
+		return errStop
+	})
+	if err != errStop || visited != 1 {
+		t.Error("EachErr: traversal should stop at the first error and return it")
+	}
+}
+
+func TestSet_EachIndexed(t *testing.T) {
+	s := newTS[string]()
+	s.Add("1", "2", "3", "4")
+
+	var indexes []int
+	s.EachIndexed(func(i int, item string) bool {
+		indexes = append(indexes, i)
+		return true
+	})
+	if !reflect.DeepEqual(indexes, []int{0, 1, 2, 3}) {
+		t.Error("EachIndexed: indexes should increase from zero, got", indexes)
+	}
+
+	visited := 0
+	s.EachIndexed(func(i int, item string) bool {
+		visited++
+		return i < 1
+	})
+	if visited != 2 {
+		t.Error("EachIndexed: traversal should stop when the closure returns false")
+	}
+}
+
+func TestSet_ContainsSet(t *testing.T) {
+	s := newTS[string]()
+	s.Add("1", "2", "3", "4")
+	u := newTS[string]()
+	u.Add("1", "2", "3")
+
+	if !s.ContainsSet(u) {
+		t.Error("ContainsSet: s contains all items of u. However it returns false")
+	}
+
+	if u.ContainsSet(s) {
+		t.Error("ContainsSet: u doesn't contain 4. However it returns true")
+	}
+}
+
+func TestSet_ContainsAnySet(t *testing.T) {
+	s := newTS[string]()
+	s.Add("1", "2", "3", "4")
+	u := newNonTS[string]()
+	u.Add("4", "5")
+	v := newTS[string]()
+	v.Add("5", "6", "7", "8", "9")
+
+	if !s.ContainsAnySet(u) {
+		t.Error("ContainsAnySet: s contains 4 of u. However it returns false")
+	}
+
+	if s.ContainsAnySet(v) {
+		t.Error("ContainsAnySet: s contains no item of v. However it returns true")
+	}
+
+	v.Add("1") // now v is the larger set to look items up in
+	if !s.ContainsAnySet(v) {
+		t.Error("ContainsAnySet: s contains 1 of v. However it returns false")
+	}
+
+	if !s.ContainsAnySet(s) || s.ContainsAnySet(newTS[string]()) {
+		t.Error("ContainsAnySet: s should contain any item of itself but not of an empty set")
+	}
+}
+
 func TestSet_String(t *testing.T) {
-	s := newTS()
+	s := newTS[any]()
 	if s.String() != "[]" {
 		t.Errorf("String: output is not what is excepted '%s'", s.String())
 	}
@@ -245,8 +801,45 @@ func TestSet_String(t *testing.T) {
 	}
 }
 
+func TestSet_StringFunc(t *testing.T) {
+	s := newTS[int]()
+	if s.StringFunc("\n", nil) != "" {
+		t.Error("StringFunc: output of an empty set should be empty")
+	}
+
+	s.Add(1)
+	if got := s.StringFunc("\n", func(n int) string { return "#" + strconv.Itoa(n) }); got != "#1" {
+		t.Error("StringFunc: item should be formatted with the given function, got", got)
+	}
+
+	s.Add(2)
+	lines := strings.Split(s.StringFunc("\n", nil), "\n")
+	sort.Strings(lines)
+	if !reflect.DeepEqual(lines, []string{"1", "2"}) {
+		t.Error("StringFunc: items should be separated by the given separator, got", lines)
+	}
+}
+
+func TestSet_Hash(t *testing.T) {
+	s := newTS[string]()
+	u := newTS[string]()
+	for i := 0; i < 100; i++ {
+		s.Add(strconv.Itoa(i))
+		u.Add(strconv.Itoa(99 - i))
+	}
+
+	if s.Hash() != u.Hash() {
+		t.Error("Hash: equal sets should have the same hash")
+	}
+
+	u.Remove("42")
+	if s.Hash() == u.Hash() {
+		t.Error("Hash: the hash should change when an item is removed")
+	}
+}
+
 func TestSet_List(t *testing.T) {
-	s := newTS()
+	s := newTS[any]()
 	s.Add("1", "2", "3", "4")
 
 	// this returns a slice of interface{}
@@ -262,8 +855,56 @@ func TestSet_List(t *testing.T) {
 	}
 }
 
+func TestSet_Stream(t *testing.T) {
+	s := newTS[string]()
+	s.Add("1", "2", "3", "4")
+
+	r := newTS[string]()
+	for item := range s.Stream() {
+		r.Add(item)
+	}
+
+	if !s.IsEqual(r) {
+		t.Error("Stream: not all items were received from the channel")
+	}
+}
+
+func TestSet_Tee(t *testing.T) {
+	s := newTS[string]()
+	s.Add("1", "2", "3", "4")
+
+	chans := s.Tee(3)
+	if len(chans) != 3 {
+		t.Fatal("Tee: there should be three channels, got", len(chans))
+	}
+
+	var wg sync.WaitGroup
+	received := make([]Set[string], len(chans))
+	for i, ch := range chans {
+		wg.Add(1)
+		go func(i int, ch <-chan string) {
+			defer wg.Done()
+			received[i] = newNonTS[string]()
+			for item := range ch {
+				received[i].Add(item)
+			}
+		}(i, ch)
+	}
+	wg.Wait()
+
+	for _, r := range received {
+		if !s.IsEqual(r) {
+			t.Error("Tee: every channel should receive all items, got", r)
+		}
+	}
+
+	if s.Tee(0) != nil {
+		t.Error("Tee: zero channels should return nil")
+	}
+}
+
 func TestSet_Copy(t *testing.T) {
-	s := newTS()
+	s := newTS[any]()
 	s.Add("1", "2", "3", "4")
 	r := s.Copy()
 
@@ -272,10 +913,66 @@ func TestSet_Copy(t *testing.T) {
 	}
 }
 
+func TestSet_SplitN(t *testing.T) {
+	s := newTS[int]()
+	for i := 0; i < 10; i++ {
+		s.Add(i)
+	}
+
+	parts := s.SplitN(3)
+	if len(parts) != 3 {
+		t.Fatal("SplitN: there should be three parts, got", len(parts))
+	}
+
+	u := newTS[int]()
+	for _, part := range parts {
+		if part.Size() < 3 || part.Size() > 4 {
+			t.Error("SplitN: parts should have three or four items, got", part.Size())
+		}
+		u.Merge(part)
+	}
+
+	if !s.IsEqual(u) {
+		t.Error("SplitN: the parts together should hold all items")
+	}
+
+	if parts := s.SplitN(0); parts != nil {
+		t.Error("SplitN: zero parts should return nil")
+	}
+}
+
+func TestSet_AsThreadSafe(t *testing.T) {
+	s := newTS[string]()
+	s.Add("1", "2", "3", "4")
+
+	r := s.AsThreadSafe()
+	if _, ok := r.(*SetTS[string]); !ok {
+		t.Errorf("AsThreadSafe: the copy should be thread-safe, got %T", r)
+	}
+
+	if !s.IsEqual(r) {
+		t.Error("AsThreadSafe: set s and r are not equal")
+	}
+}
+
+func TestSet_AsNonThreadSafe(t *testing.T) {
+	s := newTS[string]()
+	s.Add("1", "2", "3", "4")
+
+	r := s.AsNonThreadSafe()
+	if _, ok := r.(*SetNonTS[string]); !ok {
+		t.Errorf("AsNonThreadSafe: the copy should be non-thread-safe, got %T", r)
+	}
+
+	if !s.IsEqual(r) {
+		t.Error("AsNonThreadSafe: set s and r are not equal")
+	}
+}
+
 func TestSet_Merge(t *testing.T) {
-	s := newTS()
+	s := newTS[any]()
 	s.Add("1", "2", "3")
-	r := newTS()
+	r := newTS[any]()
 	r.Add("3", "4", "5")
 	s.Merge(r)
 
@@ -289,9 +986,9 @@ func TestSet_Merge(t *testing.T) {
 }
 
 func TestSet_Separate(t *testing.T) {
-	s := newTS()
+	s := newTS[any]()
 	s.Add("1", "2", "3")
-	r := newTS()
+	r := newTS[any]()
 	r.Add("3", "5")
 	s.Separate(r)
 
@@ -308,8 +1005,8 @@ func TestSet_RaceAdd(t *testing.T) {
 	// Create two sets. Add concurrently items to each of them. Remove from the
 	// other one.
 	// "go test -race" should detect this if the library is not thread-safe.
-	s := newTS()
-	u := newTS()
+	s := newTS[any]()
+	u := newTS[any]()
 
 	go func() {
 		for i := 0; i < 1000; i++ {
@@ -329,3 +1026,326 @@ func TestSet_RaceAdd(t *testing.T) {
 		}(i)
 	}
 }
+
+func TestSet_AddConcurrent(t *testing.T) {
+	// "go test -race" should detect this if the workers don't lock.
+	s := newTS[int]()
+	in := make(chan int)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 1000; j++ {
+				in <- j
+			}
+		}()
+	}
+
+	go func() {
+		wg.Wait()
+		close(in)
+	}()
+
+	s.AddConcurrent(in, 8)
+	if s.Size() != 1000 {
+		t.Error("AddConcurrent: the set should have 1000 unique items, got", s.Size())
+	}
+}
+
+func TestSet_RetainSlice(t *testing.T) {
+	s := newTS[string]()
+	s.Add("1", "2", "3", "4")
+	s.RetainSlice([]string{"2", "4", "4", "5"})
+
+	if s.Size() != 2 || !s.Has("2", "4") {
+		t.Error("RetainSlice: only the items in the slice should be retained, got", s)
+	}
+
+	s.RetainSlice(nil)
+	if !s.IsEmpty() {
+		t.Error("RetainSlice: retaining an empty slice should empty the set")
+	}
+}
+
+func TestSet_IsSubsetSlice(t *testing.T) {
+	s := newTS[string]()
+	s.Add("1", "2")
+
+	if !s.IsSubsetSlice([]string{"2", "1", "1", "3"}) {
+		t.Error("IsSubsetSlice: every item of the set is in the slice")
+	}
+	if s.IsSubsetSlice([]string{"1", "1", "3"}) {
+		t.Error("IsSubsetSlice: 2 is not in the slice")
+	}
+
+	if !s.IsSupersetSlice([]string{"2", "2", "1"}) || !s.IsSupersetSlice(nil) {
+		t.Error("IsSupersetSlice: every item of the slice is in the set")
+	}
+	if s.IsSupersetSlice([]string{"1", "3"}) {
+		t.Error("IsSupersetSlice: 3 is not in the set")
+	}
+
+	if !newTS[string]().IsSubsetSlice(nil) {
+		t.Error("IsSubsetSlice: an empty set is a subset of an empty slice")
+	}
+}
+
+func TestSet_SnapshotList(t *testing.T) {
+	s := newTS[int]()
+	s.Add(1, 2, 3)
+
+	if n, items := s.SnapshotList(); n != 3 || !EqualSlice[int](s, items) {
+		t.Error("SnapshotList: should return the size and the items, got", n, items)
+	}
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 1000; i++ {
+			s.Add(i)
+			s.Remove(i / 2)
+		}
+	}()
+	for i := 0; i < 1000; i++ {
+		if n, items := s.SnapshotList(); n != len(items) {
+			t.Fatalf("SnapshotList: size %d should match the %d items", n, len(items))
+		}
+	}
+	wg.Wait()
+}
+
+func TestSet_DifferenceSlice(t *testing.T) {
+	s := newTS[string]()
+	s.Add("1", "2", "3", "4")
+
+	u := s.DifferenceSlice([]string{"2", "4", "4", "5"})
+	if !EqualSlice(u, []string{"1", "3"}) {
+		t.Error("DifferenceSlice: only the items not in the slice should be returned, got", u)
+	}
+
+	if s.Size() != 4 {
+		t.Error("DifferenceSlice: the set should not be modified")
+	}
+}
+
+func TestSet_EachSnapshot_reentrant(t *testing.T) {
+	// Modifying s from the closure of Each deadlocks, but must not from the
+	// closure of EachSnapshot.
+	s := newTS[int]()
+	s.Add(1, 2, 3)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		s.EachSnapshot(func(item int) bool {
+			s.Remove(item)
+			s.Add(item + 10)
+			s.Merge(s.Copy())
+			return true
+		})
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("EachSnapshot: modifying the set from the closure deadlocked")
+	}
+
+	if !EqualSlice[int](s, []int{11, 12, 13}) {
+		t.Error("EachSnapshot: the modifications of the closure should be applied, got", s)
+	}
+}
+
+func TestSet_RaceIsEmpty(t *testing.T) {
+	// "go test -race" should detect this if IsEmpty doesn't lock.
+	s := newTS[int]()
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 1000; i++ {
+			s.Add(i)
+		}
+	}()
+
+	for i := 0; i < 1000; i++ {
+		s.IsEmpty()
+	}
+	wg.Wait()
+}
+
+func TestSet_RaceString(t *testing.T) {
+	// "go test -race" should detect this if String doesn't lock.
+	s := newTS[int]()
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 1000; i++ {
+			s.Add(i)
+		}
+	}()
+
+	for i := 0; i < 100; i++ {
+		_ = fmt.Sprint(s)
+	}
+	wg.Wait()
+}
+
+func TestSet_LockOrdering(t *testing.T) {
+	// Merging and comparing two sets in both directions at once, with writers
+	// pending on both, deadlocks unless the locks are taken in a fixed order.
+	a, b := newTS[int](), newTS[int]()
+	a.Add(1, 2, 3)
+	b.Add(3, 4, 5)
+
+	ops := []func(){
+		func() { a.Merge(b) },
+		func() { b.Merge(a) },
+		func() { a.IsEqual(b) },
+		func() { b.IsEqual(a) },
+		func() { a.IsSubset(b) },
+		func() { b.ContainsAnySet(a) },
+		func() { a.Merge(a) },
+		func() { a.Add(6) },
+		func() { b.Remove(6) },
+	}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+
+		var wg sync.WaitGroup
+		for _, op := range ops {
+			wg.Add(1)
+			go func(op func()) {
+				defer wg.Done()
+				for i := 0; i < 1000; i++ {
+					op()
+				}
+			}(op)
+		}
+		wg.Wait()
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(10 * time.Second):
+		t.Fatal("LockOrdering: operations on two sets in both directions deadlocked")
+	}
+
+	if !a.HasAll(1, 2, 3, 4, 5) || !b.HasAll(1, 2, 3, 4, 5) {
+		t.Error("LockOrdering: both sets should hold the items of each other, got", a, b)
+	}
+}
+
+func TestSet_RaceAllMethods(t *testing.T) {
+	// Call every method of the interface while another goroutine writes to
+	// the set. "go test -race" should detect this if any of them doesn't lock.
+	s := newTS[int]()
+	u := newTS[int]()
+	u.Add(1, 2, 3)
+
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; ; i++ {
+			select {
+			case <-done:
+				return
+			default:
+			}
+			s.Add(i % 100)
+			s.Remove((i + 50) % 100)
+		}
+	}()
+
+	yes := func(int) bool { return true }
+	drain := func(ch <-chan int) {
+		for range ch {
+		}
+	}
+
+	for i := 0; i < 10; i++ {
+		s.Add(1)
+		s.AddNew(2, 3)
+		s.Remove(4)
+		s.RemoveReturning(5)
+		s.RemoveReport(5, 11)
+		s.Pop()
+		s.PopWhere(func(n int) bool { return n == 6 })
+		s.PopHint(7)
+		s.Toggle(8)
+		s.ToggleReport(8, 9)
+		s.NormalizeInPlace(func(n int) int { return n })
+		s.Drain()
+		s.Has(1)
+		s.HasAll(1, 2)
+		s.HasAny(1, 2)
+		s.HasEach(1, 2)
+		s.Get(1)
+		s.WriteTo(io.Discard)
+		s.Intern(10)
+		s.Size()
+		s.OnSizeThreshold(10, func(int) {})
+		s.Cap()
+		s.AddAll(1, 2)
+		s.Grow(1)
+		s.EstimatedBytes()
+		s.Compact()
+		s.IsEmpty()
+		s.IsEqual(u)
+		s.IsSubset(u)
+		s.IsSuperset(u)
+		s.ContainsSet(u)
+		s.ContainsAnySet(u)
+		s.Each(yes)
+		s.EachSnapshot(yes)
+		s.EachErr(func(int) error { return nil })
+		s.EachIndexed(func(int, int) bool { return true })
+		for it := s.Iterator(); it.Next(); {
+		}
+		_ = s.String()
+		s.StringFunc(",", nil)
+		s.Hash()
+		s.List()
+		drain(s.Stream())
+		for _, ch := range s.Tee(2) {
+			drain(ch)
+		}
+		s.CopyWithCap(1)
+		for range s.Keys() {
+		}
+		s.EachBatch(2, func([]int) bool { return true })
+		s.NewEmpty()
+		s.EachParallel(2, func(int) {})
+		s.Copy()
+		s.Snapshot()
+		s.SplitN(2)
+		s.AsThreadSafe()
+		s.AsNonThreadSafe()
+		s.Merge(u)
+		s.Separate(u)
+		s.RetainSlice([]int{1, 2, 3, 4, 5, 6, 7, 8, 9})
+		s.IsSubsetSlice([]int{1, 2})
+		s.IsSupersetSlice([]int{1, 2})
+		s.DifferenceSlice([]int{1})
+		u.IsEqual(s)
+		u.IsSubset(s)
+		u.IsSuperset(s)
+		u.Merge(s)
+		u.Separate(s)
+		u.Add(1, 2, 3)
+		s.Clear()
+	}
+
+	close(done)
+	wg.Wait()
+}
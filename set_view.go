@@ -0,0 +1,442 @@
+package set
+
+import (
+	"fmt"
+	"io"
+	"iter"
+	"slices"
+	"strings"
+)
+
+// SetView defines a read-only set data structure backed directly by a slice,
+// to pass a slice where a Set is expected without copying it into a map.
+// Membership is tested by a linear scan of the slice, so Has costs O(n) and
+// comparing a view with another set O(n*m); it only pays off for small slices
+// or sets few lookups are made in. Items are traversed in the order of the
+// slice, regardless of SetIterationSeed. All methods which would modify it
+// panic.
+type SetView[T comparable] struct {
+	items []T
+}
+
+// ViewSlice returns a read-only Set backed by items, without copying them.
+// items must not hold duplicates, which aren't checked for: with them, the
+// results of Size, IsEqual, Hash, SplitN and the like are undefined. Nor must
+// items be modified while the view is in use. See SetView for the cost of its
+// methods.
+func ViewSlice[T comparable](items []T) Set[T] {
+	s := &SetView[T]{items: items}
+
+	// Ensure interface compliance
+	var _ Set[T] = s
+
+	return s
+}
+
+// viewOnly panics, reporting that method was called on a slice view.
+func viewOnly(method string) {
+	panic("set: " + method + " called on a slice view")
+}
+
+// NewEmpty returns a new empty non-thread-safe Set, as an empty view couldn't
+// be filled.
+func (s *SetView[T]) NewEmpty() Set[T] {
+	return newNonTS[T]()
+}
+
+// Add panics, as a view can't be modified.
+func (s *SetView[T]) Add(items ...T) { viewOnly("Add") }
+
+// AddAll panics, as a view can't be modified.
+func (s *SetView[T]) AddAll(items ...T) { viewOnly("AddAll") }
+
+// AddNew panics, as a view can't be modified.
+func (s *SetView[T]) AddNew(items ...T) Set[T] { viewOnly("AddNew"); return nil }
+
+// Intern panics, as a view can't be modified.
+func (s *SetView[T]) Intern(item T) T { viewOnly("Intern"); return item }
+
+// Toggle panics, as a view can't be modified.
+func (s *SetView[T]) Toggle(items ...T) { viewOnly("Toggle") }
+
+// ToggleReport panics, as a view can't be modified.
+func (s *SetView[T]) ToggleReport(items ...T) []bool { viewOnly("ToggleReport"); return nil }
+
+// Remove panics, as a view can't be modified.
+func (s *SetView[T]) Remove(items ...T) { viewOnly("Remove") }
+
+// RemoveReturning panics, as a view can't be modified.
+func (s *SetView[T]) RemoveReturning(items ...T) Set[T] { viewOnly("RemoveReturning"); return nil }
+
+// RemoveReport panics, as a view can't be modified.
+func (s *SetView[T]) RemoveReport(items ...T) (removed, notFound []T) {
+	viewOnly("RemoveReport")
+	return nil, nil
+}
+
+// Pop panics, as a view can't be modified.
+func (s *SetView[T]) Pop() (T, bool) {
+	viewOnly("Pop")
+	var zeroVal T
+	return zeroVal, false
+}
+
+// PopHint panics, as a view can't be modified.
+func (s *SetView[T]) PopHint(hint T) (T, bool) {
+	viewOnly("PopHint")
+	var zeroVal T
+	return zeroVal, false
+}
+
+// PopWhere panics, as a view can't be modified.
+func (s *SetView[T]) PopWhere(pred func(T) bool) Set[T] { viewOnly("PopWhere"); return nil }
+
+// OnSizeThreshold does nothing, as a view never grows.
+func (s *SetView[T]) OnSizeThreshold(n int, f func(size int)) {}
+
+// Grow panics, as a view can't be modified.
+func (s *SetView[T]) Grow(n int) { viewOnly("Grow") }
+
+// Clear panics, as a view can't be modified.
+func (s *SetView[T]) Clear() { viewOnly("Clear") }
+
+// Drain panics, as a view can't be modified.
+func (s *SetView[T]) Drain() []T { viewOnly("Drain"); return nil }
+
+// Compact panics, as a view can't be modified.
+func (s *SetView[T]) Compact() { viewOnly("Compact") }
+
+// NormalizeInPlace panics, as a view can't be modified.
+func (s *SetView[T]) NormalizeInPlace(f func(item T) T) { viewOnly("NormalizeInPlace") }
+
+// Merge panics, as a view can't be modified.
+func (s *SetView[T]) Merge(t Set[T]) { viewOnly("Merge") }
+
+// Separate panics, as a view can't be modified.
+func (s *SetView[T]) Separate(t Set[T]) { viewOnly("Separate") }
+
+// RetainSlice panics, as a view can't be modified.
+func (s *SetView[T]) RetainSlice(items []T) { viewOnly("RetainSlice") }
+
+// has reports whether item is in s, scanning the slice.
+func (s *SetView[T]) has(item T) bool {
+	return slices.Contains(s.items, item)
+}
+
+// Has looks for the existence of items passed. It returns false if nothing is
+// passed. For multiple items it returns true only if all of  the items exist.
+func (s *SetView[T]) Has(items ...T) bool {
+	if len(items) == 0 {
+		return false
+	}
+
+	for _, item := range items {
+		if !s.has(item) {
+			return false
+		}
+	}
+	return true
+}
+
+// HasAll is an explicit alias of Has. It returns true only if all of the
+// passed items exist, and false if nothing is passed.
+func (s *SetView[T]) HasAll(items ...T) bool {
+	return s.Has(items...)
+}
+
+// HasAny looks for the existence of items passed. It returns false if nothing
+// is passed. For multiple items it returns true if at least one of the items
+// exists.
+func (s *SetView[T]) HasAny(items ...T) bool {
+	return slices.ContainsFunc(items, s.has)
+}
+
+// HasEach looks for the existence of each item passed. It returns a slice
+// reporting the membership of each item, in the same order as the items.
+func (s *SetView[T]) HasEach(items ...T) []bool {
+	has := make([]bool, len(items))
+	for i, item := range items {
+		has[i] = s.has(item)
+	}
+	return has
+}
+
+// Get looks up item, returning the item of the slice equal to it and true, or
+// the zero value and false. The item returned is the one in the slice, not
+// the argument.
+func (s *SetView[T]) Get(item T) (T, bool) {
+	if i := slices.Index(s.items, item); i >= 0 {
+		return s.items[i], true
+	}
+	var zeroVal T
+	return zeroVal, false
+}
+
+// Size returns the number of items in a set.
+func (s *SetView[T]) Size() int {
+	return len(s.items)
+}
+
+// Cap returns the size of the view, as it can't hold more items.
+func (s *SetView[T]) Cap() int {
+	return len(s.items)
+}
+
+// EstimatedBytes returns a rough estimate of the heap size of the view itself,
+// which is only the slice header, as the slice is shared with the caller.
+func (s *SetView[T]) EstimatedBytes() int {
+	return 24
+}
+
+// IsEmpty reports whether the Set is empty.
+func (s *SetView[T]) IsEmpty() bool {
+	return len(s.items) == 0
+}
+
+// IsEqual test whether s and t are the same in size and have the same items.
+func (s *SetView[T]) IsEqual(t Set[T]) bool {
+	t, unlock := readLocked(t)
+	defer unlock()
+
+	if len(s.items) != t.Size() {
+		return false
+	}
+	return !slices.ContainsFunc(s.items, func(item T) bool { return !t.Has(item) })
+}
+
+// IsSubset tests whether t is a subset of s.
+func (s *SetView[T]) IsSubset(t Set[T]) (subset bool) {
+	t, unlock := readLocked(t)
+	defer unlock()
+
+	if t.Size() > len(s.items) {
+		return false
+	}
+
+	subset = true
+	t.Each(func(item T) bool {
+		subset = s.has(item)
+		return subset
+	})
+	return subset
+}
+
+// IsSuperset tests whether t is a superset of s.
+func (s *SetView[T]) IsSuperset(t Set[T]) bool {
+	return orEmpty(t).IsSubset(s)
+}
+
+// ContainsSet tests whether every item of t is in s. It's the same as
+// s.IsSubset(t), which reads less naturally.
+func (s *SetView[T]) ContainsSet(t Set[T]) bool {
+	return s.IsSubset(t)
+}
+
+// ContainsAnySet tests whether any item of t is in s.
+func (s *SetView[T]) ContainsAnySet(t Set[T]) bool {
+	t, unlock := readLocked(t)
+	defer unlock()
+
+	return slices.ContainsFunc(s.items, func(item T) bool { return t.Has(item) })
+}
+
+// Each traverses the items in the Set in the order of the slice, calling the
+// provided function for each set member. Traversal will continue until all
+// items in the Set have been visited, or if the closure returns false.
+func (s *SetView[T]) Each(f func(item T) bool) {
+	eachOf(s.items, f)
+}
+
+// EachSnapshot is the same as Each, as a view can't be modified.
+func (s *SetView[T]) EachSnapshot(f func(item T) bool) {
+	eachOf(s.items, f)
+}
+
+// EachErr traverses the items in the Set, calling the provided function for
+// each set member. Traversal stops at the first error returned by the closure,
+// which is then returned. A nil error means all items have been visited.
+func (s *SetView[T]) EachErr(f func(item T) error) error {
+	for _, item := range s.items {
+		if err := f(item); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// EachIndexed is like Each, but also passes the index of each item in the
+// slice to the closure.
+func (s *SetView[T]) EachIndexed(f func(i int, item T) bool) {
+	for i, item := range s.items {
+		if !f(i, item) {
+			break
+		}
+	}
+}
+
+// EachBatch traverses the items in the Set in the order of the slice, passing
+// them to the closure in batches of up to batchSize items. See set.EachBatch
+// for details.
+func (s *SetView[T]) EachBatch(batchSize int, f func(batch []T) bool) {
+	eachBatch(s.Each, batchSize, f)
+}
+
+// EachParallel calls f for each item of the set from workers goroutines at
+// once. See set.EachParallel for details.
+func (s *SetView[T]) EachParallel(workers int, f func(item T)) {
+	eachParallel(s.items, workers, f)
+}
+
+// Iterator returns an iterator over a copy of the items of the set.
+func (s *SetView[T]) Iterator() *Iterator[T] {
+	return newIterator(s.List())
+}
+
+// Keys returns a sequence of the items of the set in the order of the slice.
+func (s *SetView[T]) Keys() iter.Seq[T] {
+	return slices.Values(s.items)
+}
+
+// String returns a string representation of s
+func (s *SetView[T]) String() string {
+	return "[" + s.StringFunc(", ", nil) + "]"
+}
+
+// StringFunc returns a string representation of s, formatting each item with
+// format and separating them with sep. Unlike String, the result isn't
+// enclosed in square brackets. If format is nil, items are formatted with %v.
+func (s *SetView[T]) StringFunc(sep string, format func(T) string) string {
+	if format == nil {
+		format = func(item T) string { return fmt.Sprintf("%v", item) }
+	}
+
+	t := make([]string, 0, len(s.items))
+	for _, item := range s.items {
+		t = append(t, format(item))
+	}
+
+	return strings.Join(t, sep)
+}
+
+// WriteTo writes the items of s to w in the order of the slice, one per
+// line. See set.WriteTo for details.
+func (s *SetView[T]) WriteTo(w io.Writer) (int64, error) {
+	return writeItems(w, s.items)
+}
+
+// Hash returns a hash of the items of s, which is the same as that of a map
+// set with the same items. See set.Hash for details. Unlike there, the hash
+// isn't cached.
+func (s *SetView[T]) Hash() uint64 {
+	var sum uint64
+	for _, item := range s.items {
+		sum += hashItem(item)
+	}
+	return sum
+}
+
+// List returns a copy of the slice backing the view.
+func (s *SetView[T]) List() []T {
+	return slices.Clone(s.items)
+}
+
+// Stream returns a channel over which all items of the set are sent, after
+// which the channel is closed.
+func (s *SetView[T]) Stream() <-chan T {
+	return stream(s.items)
+}
+
+// Tee returns n channels, over each of which all items of the set are sent,
+// after which the channels are closed. See set.Tee for details.
+func (s *SetView[T]) Tee(n int) []<-chan T {
+	return tee(s.items, n)
+}
+
+// toMap returns a new map holding the items of s, with room for extra more.
+func (s *SetView[T]) toMap(extra int) map[T]T {
+	m := make(map[T]T, len(s.items)+max(extra, 0))
+	for _, item := range s.items {
+		m[item] = item
+	}
+	return m
+}
+
+// Copy returns a new non-thread-safe Set with a copy of s, backed by a map.
+func (s *SetView[T]) Copy() Set[T] {
+	return s.CopyWithCap(0)
+}
+
+// CopyWithCap returns a new non-thread-safe Set with a copy of s, like Copy,
+// with room for extra more items reserved. extra is a hint, not a limit: the
+// copy grows past it as needed.
+func (s *SetView[T]) CopyWithCap(extra int) Set[T] {
+	u := newNonTS[T]()
+	u.m = s.toMap(extra)
+	u.hint = len(s.items) + max(extra, 0)
+	return u
+}
+
+// Snapshot returns a read-only copy of s, backed by a map, which is unaffected
+// by later modifications of the slice.
+func (s *SetView[T]) Snapshot() Set[T] {
+	return newFrozen(s.toMap(0))
+}
+
+// SplitN partitions the items of s into n new non-thread-safe sets, e.g. to
+// process them in parallel. Each item ends up in exactly one of the sets, and
+// their sizes differ by at most one. If n is less than one, nil is returned.
+func (s *SetView[T]) SplitN(n int) []Set[T] {
+	return splitN(s.items, n, func() Set[T] { return newNonTS[T]() })
+}
+
+// AsThreadSafe returns a new thread-safe Set with a copy of s, backed by a
+// map.
+func (s *SetView[T]) AsThreadSafe() Set[T] {
+	u := newTS[T]()
+	u.m = s.toMap(0)
+	return u
+}
+
+// AsNonThreadSafe returns a new non-thread-safe Set with a copy of s, backed
+// by a map.
+func (s *SetView[T]) AsNonThreadSafe() Set[T] {
+	return s.CopyWithCap(0)
+}
+
+// DifferenceSlice returns a new non-thread-safe set which contains the items
+// of s which don't appear in items.
+func (s *SetView[T]) DifferenceSlice(items []T) Set[T] {
+	drop := make(map[T]struct{}, len(items))
+	for _, item := range items {
+		drop[item] = keyExists
+	}
+
+	u := newNonTS[T]()
+	for _, item := range s.items {
+		if _, ok := drop[item]; !ok {
+			u.m[item] = item
+		}
+	}
+	return u
+}
+
+// IsSubsetSlice tests whether every item of s appears in items. See
+// set.IsSubsetSlice for details.
+func (s *SetView[T]) IsSubsetSlice(items []T) bool {
+	found := make(map[T]struct{}, min(len(s.items), len(items)))
+	for _, item := range items {
+		if len(found) == len(s.items) {
+			break
+		}
+		if s.has(item) {
+			found[item] = keyExists
+		}
+	}
+	return len(found) == len(s.items)
+}
+
+// IsSupersetSlice tests whether every one of items is in s. See
+// set.IsSupersetSlice for details.
+func (s *SetView[T]) IsSupersetSlice(items []T) bool {
+	return !slices.ContainsFunc(items, func(item T) bool { return !s.has(item) })
+}
@@ -0,0 +1,100 @@
+package set
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSetView_Has(t *testing.T) {
+	items := []string{"c", "a", "b"}
+	s := ViewSlice(items)
+
+	if s.Size() != 3 || !s.Has("a", "b", "c") || s.Has("d") || !s.HasAny("d", "a") {
+		t.Error("Has: the view should hold the items of the slice, got", s)
+	}
+
+	if !reflect.DeepEqual(s.List(), items) {
+		t.Error("List: items should be in the order of the slice, got", s.List())
+	}
+
+	items[0] = "d" // no copy is made
+	if !s.Has("d") || s.Has("c") {
+		t.Error("ViewSlice: the view should be backed by the slice")
+	}
+
+	if !s.IsSubsetSlice([]string{"d", "b", "a", "a"}) || !s.IsSupersetSlice([]string{"d", "d"}) || s.IsSupersetSlice([]string{"c"}) {
+		t.Error("IsSubsetSlice: wrong result against a slice")
+	}
+
+	if !ViewSlice[int](nil).IsEmpty() {
+		t.Error("ViewSlice: a view of a nil slice should be empty")
+	}
+}
+
+func TestSetView_Compare(t *testing.T) {
+	s := ViewSlice([]int{1, 2, 3})
+	u := newTS[int]()
+	u.Add(3, 2, 1)
+
+	if !s.IsEqual(u) || !u.IsEqual(s) {
+		t.Error("IsEqual: should equal a map set with the same items")
+	}
+
+	if s.Hash() != u.Hash() {
+		t.Error("Hash: should equal the hash of a map set with the same items")
+	}
+
+	u.Remove(1)
+	if !s.IsSubset(u) || !u.IsSuperset(s) || !s.ContainsAnySet(u) {
+		t.Error("IsSubset: wrong result against a map set")
+	}
+
+	c := s.Copy()
+	c.Add(4)
+	if s.Size() != 3 || !Union[int](s, u).IsEqual(s) || !Difference[int](c, s).Has(4) {
+		t.Error("Copy: should be a modifiable copy of the view, got", c)
+	}
+}
+
+func TestSetView_Modify(t *testing.T) {
+	s := ViewSlice([]int{1, 2, 3})
+
+	mods := map[string]func(){
+		"Add":              func() { s.Add(4) },
+		"AddNew":           func() { s.AddNew(4) },
+		"Intern":           func() { s.Intern(4) },
+		"Remove":           func() { s.Remove(1) },
+		"RemoveReturning":  func() { s.RemoveReturning(1) },
+		"RemoveReport":     func() { s.RemoveReport(1) },
+		"Pop":              func() { s.Pop() },
+		"PopWhere":         func() { s.PopWhere(func(int) bool { return true }) },
+		"PopHint":          func() { s.PopHint(1) },
+		"Toggle":           func() { s.Toggle(1) },
+		"ToggleReport":     func() { s.ToggleReport(1) },
+		"AddAll":           func() { s.AddAll(1) },
+		"Grow":             func() { s.Grow(1) },
+		"Clear":            func() { s.Clear() },
+		"Drain":            func() { s.Drain() },
+		"Compact":          func() { s.Compact() },
+		"NormalizeInPlace": func() { s.NormalizeInPlace(func(n int) int { return n }) },
+		"Merge":            func() { s.Merge(s) },
+		"Separate":         func() { s.Separate(s) },
+		"RetainSlice":      func() { s.RetainSlice(nil) },
+	}
+
+	for method, mod := range mods {
+		func() {
+			defer func() {
+				if msg, _ := recover().(string); !strings.Contains(msg, method) {
+					t.Errorf("%s: should panic about a slice view, got %q", method, msg)
+				}
+			}()
+			mod()
+		}()
+	}
+
+	if s.Size() != 3 {
+		t.Error("SetView: the view should not be modified, got", s)
+	}
+}